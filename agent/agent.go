@@ -0,0 +1,482 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/metachris/flashbotsrpc"
+	"go-bundles-go/arrival"
+	"go-bundles-go/chain"
+	"go-bundles-go/contracts/mevsim"
+	"go-bundles-go/ratelimit"
+	"go-bundles-go/relay"
+	"go-bundles-go/valuation"
+	"go-bundles-go/wallet"
+	"golang.org/x/time/rate"
+	"math/big"
+	"sync/atomic"
+	"time"
+)
+
+// Config is bidding setup of a single agent
+type Config struct {
+	// auction target
+	Slot *big.Int
+
+	// bid parameters, nil Strategy starts at StartingEffGasPrice and raises it by IncrementEffGasPrice every bid
+	StartingEffGasPrice  *big.Int
+	IncrementEffGasPrice *big.Int
+	Strategy             Strategy
+	// optional private value of winning the slot drawn every block, bids never pay more than it
+	Valuation valuation.Distribution
+	// optional record of drawn valuations for profit tracking, can be shared by agents
+	Valuations *valuation.Ledger
+	// optional rival bids given to Strategy, shared by agents
+	Observations *Observations
+	BidRate      uint64 // bids per second, 0 leaves rate to Scheduler
+	// optional arrival times of bids, nil sends bids at regular BidRate ticks
+	Arrival        arrival.Process
+	TxType         uint8
+	AccessListMode string // AccessListStatic or AccessListRPC, other values send no access list
+	// estimate gas without access list too and log the difference, two extra gas estimations every block
+	LogAccessListGas bool
+	// time between blocks, 0 uses DefaultSlotDuration
+	SlotDuration time.Duration
+	// part of the slot bids are sent in, zero value bids during the whole slot
+	Window BidWindow
+	// extra gas burned and calldata bytes sent with every bid, uses auctionWithBurn if any is set
+	BurnGas      uint64
+	PaddingBytes int
+
+	// fixed gas limit of bids, 0 means estimate every block with GasEstimateMode and apply GasMultiplier
+	GasLimit        uint64
+	GasEstimateMode string
+	GasMultiplier   float64
+
+	ChainID *big.Int
+
+	Signer wallet.TxSigner
+	// signs relay requests
+	RelayKey *ecdsa.PrivateKey
+
+	// optional fair or priority sharing of bids with other agents, every bid waits for a token of Group.
+	// Relay request limits are held by relay client.
+	Scheduler *ratelimit.Scheduler
+	Group     int
+
+	// handling of relay and node errors by class, nil uses relay.DefaultPolicies
+	ErrorPolicies map[relay.ErrorClass]relay.Policy
+	// optional counters of node request errors, can be shared by agents
+	NodeStats *relay.Stats
+	// optional shared source of block state, nil makes the agent read it with its own calls
+	State StateSource
+}
+
+// BundleAgent simulates mev searcher activity by sending bids for MevSim slot as bundles
+type BundleAgent struct {
+	Config
+
+	// set by funding supervisor when wallet can't afford the next bid
+	paused atomic.Bool
+	// max fee of the next bid, nil until the first bid is built
+	nextBidCost atomic.Pointer[big.Int]
+}
+
+func New(config Config) *BundleAgent {
+	return &BundleAgent{Config: config}
+}
+
+func (b *BundleAgent) Address() common.Address {
+	return b.Signer.Address()
+}
+
+func (b *BundleAgent) Paused() bool {
+	return b.paused.Load()
+}
+
+func (b *BundleAgent) SetPaused(paused bool) {
+	b.paused.Store(paused)
+}
+
+// NextBidCost returns max amount of wei the next bid can spend or nil if it's unknown yet
+func (b *BundleAgent) NextBidCost() *big.Int {
+	return b.nextBidCost.Load()
+}
+
+// Run sends bids for the next block to relay until ctx is canceled
+func (b *BundleAgent) Run(ctx context.Context, client chain.Backend, bundleRelay relay.Client, mevsimAddr common.Address) error {
+	bundleAgentAddress := b.Signer.Address()
+
+	mevsimAbi, err := mevsim.MevSimMetaData.GetAbi()
+	if err != nil {
+		return err
+	}
+
+	mevsimContract, err := mevsim.NewMevSim(mevsimAddr, client)
+	if err != nil {
+		return err
+	}
+	mevsimSession := mevsim.MevSimSession{
+		Contract: mevsimContract,
+		CallOpts: bind.CallOpts{
+			Pending: false,
+			From:    bundleAgentAddress,
+		},
+	}
+
+	var (
+		head           = chain.NewHeadTracker()
+		slot           SlotState
+		lastSlotValue  *big.Int
+		lastBaseFee    *big.Int
+		lastNonce      uint64
+		lastData       []byte
+		lastAccessList types.AccessList
+		lastGasLimit   uint64
+
+		sentBundles uint64
+		// consecutive failures to get the latest block
+		nodeFailures int
+	)
+
+	policies := b.ErrorPolicies
+	if policies == nil {
+		policies = relay.DefaultPolicies()
+	}
+	strategy := b.bidStrategy()
+	slotDuration := b.slotDuration()
+	windowStart, windowEnd := b.Window.Bounds(slotDuration)
+
+	var limiter interface {
+		Wait(ctx context.Context) error
+	}
+	if b.Arrival != nil {
+		limiter = arrival.NewPacer(b.Arrival)
+	} else if b.BidRate == 0 {
+		limiter = rate.NewLimiter(rate.Inf, 1)
+	} else {
+		limiter = rate.NewLimiter(rate.Limit(b.BidRate), 1)
+	}
+	for {
+		err = limiter.Wait(ctx)
+		if err != nil {
+			return err
+		}
+
+		// get current block number
+		var header *types.Header
+		var state *BlockState
+		if b.State != nil {
+			state = b.State.Latest()
+			if state == nil {
+				continue
+			}
+			header = state.Header
+		} else {
+			header, err = client.HeaderByNumber(ctx, nil)
+			b.NodeStats.Record(err)
+			if err != nil {
+				class := relay.Classify(err)
+				policy := policies[class]
+				if nodeFailures == 0 {
+					fmt.Println("error getting block", "class", class, "error", err)
+				}
+				if policy.Fatal {
+					return err
+				}
+				nodeFailures++
+				// node is polled on every tick, retries only back off
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(policy.Backoff(nodeFailures)):
+				}
+				continue
+			}
+			if nodeFailures > 0 {
+				fmt.Println("node recovered", "failures", nodeFailures)
+				nodeFailures = 0
+			}
+		}
+		blockNumber := header.Number.Uint64()
+		if changed, reorg := head.Compare(header); changed {
+			if reorg != nil {
+				// slot value and nonce read at the replaced head are stale, bundles built on them would revert
+				fmt.Println("reorg detected", "depth", reorg.Depth, "oldBlock", reorg.OldNumber, "oldHash", reorg.OldHash.Hex(),
+					"newBlock", reorg.NewNumber, "newHash", reorg.NewHash.Hex())
+			}
+			fmt.Println("switching to new block", blockNumber, "sentBundlesPrevBlock", sentBundles)
+			if learner, ok := strategy.(Learner); ok && sentBundles > 0 && slot.TargetBlock != 0 && blockNumber >= slot.TargetBlock {
+				outcome, err := b.slotOutcome(ctx, mevsimContract, bundleAgentAddress, slot)
+				if err != nil {
+					fmt.Println("error getting slot outcome", err)
+				} else {
+					learner.Outcome(outcome)
+				}
+				slot = SlotState{}
+			}
+			lastSlotValue, lastNonce, lastBaseFee, err = b.blockState(ctx, client, &mevsimSession, state)
+			if err != nil {
+				fmt.Println("error reading block state", err)
+				continue
+			}
+			lastData, err = b.auctionCallData(mevsimAbi, lastSlotValue, blockNumber+1)
+			if err != nil {
+				fmt.Println("error packing tx data", err)
+				continue
+			}
+			lastAccessList, err = b.auctionAccessList(ctx, client, mevsimAddr, header.Coinbase, lastData)
+			if err != nil {
+				fmt.Println("error creating access list", err)
+				continue
+			}
+			estimatedGas := uint64(0)
+			slot = SlotState{
+				TargetBlock: blockNumber + 1,
+				ParentTime:  time.Unix(int64(header.Time), 0),
+				Duration:    slotDuration,
+				Now:         time.Now(),
+			}
+			lastGasLimit = b.GasLimit
+			if lastGasLimit == 0 {
+				switch b.GasEstimateMode {
+				case GasEstimateCallBundle:
+					// simulated with the first bid of the slot
+					simEffGasPrice := strategy.Bid(slot)
+					if simEffGasPrice == nil {
+						simEffGasPrice = big.NewInt(0)
+					}
+					var simTx *types.Transaction
+					simTx, err = b.Signer.SignTx(chain.NewBidTx(b.TxType, b.ChainID, lastNonce, mevsimAddr, CallBundleGasLimit, lastBaseFee, simEffGasPrice, big.NewInt(0), lastData, lastAccessList), b.ChainID)
+					if err == nil {
+						estimatedGas, err = relay.CallBundleGasUsed(bundleRelay, b.RelayKey, simTx, blockNumber+1)
+					}
+				default:
+					estimatedGas, err = estimateGas(ctx, client, ethereum.CallMsg{
+						From:       bundleAgentAddress,
+						To:         &mevsimAddr,
+						Data:       lastData,
+						AccessList: lastAccessList,
+					})
+				}
+				if err != nil {
+					fmt.Println("error estimating gas", err)
+					if policies[relay.Classify(err)].Fatal {
+						return err
+					}
+					continue
+				}
+				lastGasLimit = chain.ApplyGasMultiplier(estimatedGas, b.GasMultiplier)
+			}
+			if b.Valuation != nil {
+				// fee cap times gas limit bounds what the bid can pay
+				slot.Valuation = b.Valuation.Draw()
+				slot.MaxEffGasPrice = new(big.Int).Div(slot.Valuation, new(big.Int).SetUint64(lastGasLimit))
+				slot.MaxEffGasPrice.Sub(slot.MaxEffGasPrice, lastBaseFee)
+				b.Valuations.Record(slot.TargetBlock, b.Slot, bundleAgentAddress, slot.Valuation)
+			}
+			head.Accept(header)
+			sentBundles = 0
+			fmt.Println("slot timing", "targetBlock", slot.TargetBlock, "untilNextBlock", slot.UntilNextBlock().Round(time.Millisecond))
+			if slot.Valuation != nil {
+				fmt.Println("slot valuation", "targetBlock", slot.TargetBlock, "valuation(eth)", chain.WeiToUnit(slot.Valuation, 1e18),
+					"maxEffGasPrice(gwei)", chain.WeiToUnit(slot.MaxEffGasPrice, 1e9))
+			}
+		}
+
+		slot.Now = time.Now()
+		if elapsed := slot.Elapsed(); elapsed < windowStart {
+			err = sleep(ctx, windowStart-elapsed)
+			if err != nil {
+				return err
+			}
+			continue
+		} else if elapsed >= windowEnd {
+			// deadline passed, wait for the next block
+			err = sleep(ctx, slot.UntilNextBlock())
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if b.Observations != nil {
+			slot.PrevWinningTip, slot.PrevWinnerOurs = nil, false
+			if tip, block, bidder := b.Observations.LastWinner(b.Slot); tip != nil && block < slot.TargetBlock {
+				slot.PrevWinningTip, slot.PrevWinnerOurs = tip, bidder == bundleAgentAddress
+			}
+			slot.RivalPendingTip = b.Observations.PendingBest(b.Slot, slot.TargetBlock, bundleAgentAddress)
+		}
+		effGasPrice := strategy.Bid(slot)
+		if effGasPrice == nil {
+			continue
+		}
+		if slot.MaxEffGasPrice != nil && effGasPrice.Cmp(slot.MaxEffGasPrice) > 0 {
+			if slot.MaxEffGasPrice.Sign() < 0 {
+				// valuation doesn't cover the base fee
+				continue
+			}
+			effGasPrice = slot.MaxEffGasPrice
+		}
+		slot.Bids++
+		gasLimit := lastGasLimit
+
+		nextBidCost := new(big.Int).Add(lastBaseFee, effGasPrice)
+		b.nextBidCost.Store(nextBidCost.Mul(nextBidCost, new(big.Int).SetUint64(gasLimit)))
+		if b.Paused() {
+			continue
+		}
+		if b.Scheduler != nil {
+			err = b.Scheduler.Wait(ctx, b.Group)
+			if err != nil {
+				return err
+			}
+		}
+
+		tx, err := b.Signer.SignTx(chain.NewBidTx(b.TxType, b.ChainID, lastNonce, mevsimAddr, gasLimit, lastBaseFee, effGasPrice, big.NewInt(0), lastData, lastAccessList), b.ChainID)
+		if err != nil {
+			fmt.Println("error signing tx", err)
+			continue
+		}
+
+		txBytes, err := tx.MarshalBinary()
+		if err != nil {
+			fmt.Println("error marshalling tx", err)
+			continue
+		}
+
+		//send tx as a bundle
+		callBundleArgs := flashbotsrpc.FlashbotsSendBundleRequest{
+			Txs:         []string{fmt.Sprintf("0x%s", common.Bytes2Hex(txBytes))},
+			BlockNumber: fmt.Sprintf("0x%x", blockNumber+1),
+		}
+
+		_, err = bundleRelay.FlashbotsSendBundle(b.RelayKey, callBundleArgs)
+		if err != nil {
+			class := relay.Classify(err)
+			if class != relay.ClassCircuitOpen {
+				fmt.Println("error sending bundle", "class", class, "error", err)
+			}
+			if policies[class].Fatal {
+				return fmt.Errorf("giving up on relay: %w", err)
+			}
+			continue
+		}
+
+		sentBundles++
+	}
+}
+
+// slotOutcome checks if bidder won the slot in its target block
+func (b *BundleAgent) slotOutcome(ctx context.Context, mevsimContract *mevsim.MevSim, bidder common.Address, slot SlotState) (Outcome, error) {
+	outcome := Outcome{TargetBlock: slot.TargetBlock, Valuation: slot.Valuation, MaxEffGasPrice: slot.MaxEffGasPrice}
+	end := slot.TargetBlock
+	it, err := mevsimContract.FilterAuctioned(&bind.FilterOpts{Start: slot.TargetBlock, End: &end, Context: ctx}, []*big.Int{b.Slot}, []common.Address{bidder})
+	if err != nil {
+		return outcome, err
+	}
+	defer it.Close()
+	for it.Next() {
+		outcome.Won = true
+		outcome.TipPerGas = it.Event.TipPerGas
+	}
+	return outcome, it.Error()
+}
+
+func (b *BundleAgent) slotDuration() time.Duration {
+	if b.SlotDuration == 0 {
+		return DefaultSlotDuration
+	}
+	return b.SlotDuration
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// bidStrategy returns Strategy, linear from starting price and increment if it's not set
+func (b *BundleAgent) bidStrategy() Strategy {
+	if b.Strategy == nil {
+		return LinearStrategy{Start: b.StartingEffGasPrice, Increment: b.IncrementEffGasPrice}
+	}
+	return b.Strategy
+}
+
+func (b *BundleAgent) auctionCallData(mevsimAbi *abi.ABI, slotValue *big.Int, targetBlock uint64) ([]byte, error) {
+	if b.BurnGas == 0 && b.PaddingBytes == 0 {
+		return mevsimAbi.Pack("auction", b.Slot, slotValue, new(big.Int).SetUint64(targetBlock))
+	}
+	padding := bytes.Repeat([]byte{0xff}, b.PaddingBytes)
+	return mevsimAbi.Pack("auctionWithBurn", b.Slot, slotValue, new(big.Int).SetUint64(targetBlock), new(big.Int).SetUint64(b.BurnGas), padding)
+}
+
+// auctionAccessList builds access list for auction call data, nil unless AccessListMode is static or rpc.
+// coinbase is coinbase of the head, used by static access list as the best guess of the next block's.
+func (b *BundleAgent) auctionAccessList(ctx context.Context, client chain.Backend, mevsimAddr common.Address, coinbase common.Address, data []byte) (types.AccessList, error) {
+	msg := ethereum.CallMsg{
+		From: b.Signer.Address(),
+		To:   &mevsimAddr,
+		Data: data,
+	}
+
+	var accessList types.AccessList
+	switch b.AccessListMode {
+	case AccessListStatic:
+		accessList = mevsim.StaticAuctionAccessList(mevsimAddr, b.Slot, coinbase)
+	case AccessListRPC:
+		alBackend, ok := client.(chain.AccessListBackend)
+		if !ok {
+			return nil, fmt.Errorf("backend can't create access lists")
+		}
+		var err error
+		accessList, err = alBackend.CreateAccessList(ctx, msg)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
+	}
+
+	if b.LogAccessListGas {
+		b.logAccessListGas(ctx, client, msg, accessList)
+	}
+	return accessList, nil
+}
+
+// logAccessListGas logs gas used by msg with and without access list
+func (b *BundleAgent) logAccessListGas(ctx context.Context, client chain.Backend, msg ethereum.CallMsg, accessList types.AccessList) {
+	gasWithout, err := client.EstimateGas(ctx, msg)
+	if err != nil {
+		fmt.Println("error estimating gas without access list", err)
+		return
+	}
+	msg.AccessList = accessList
+	gasWith, err := estimateGas(ctx, client, msg)
+	if err != nil {
+		fmt.Println("error estimating gas with access list", err)
+		return
+	}
+	fmt.Println("access list", b.AccessListMode, "slot", b.Slot, "entries", len(accessList),
+		"gasWithout", gasWithout, "gasWith", gasWith, "gasDiff", int64(gasWith)-int64(gasWithout))
+}
+
+// estimateGas estimates gas of msg including its access list, ethclient drops access list so it's sent by AccessListBackend if possible
+func estimateGas(ctx context.Context, client chain.Backend, msg ethereum.CallMsg) (uint64, error) {
+	if alBackend, ok := client.(chain.AccessListBackend); ok {
+		return alBackend.EstimateGasWithAccessList(ctx, msg)
+	}
+	return client.EstimateGas(ctx, msg)
+}
@@ -0,0 +1,457 @@
+package agent
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"go-bundles-go/arrival"
+	"go-bundles-go/contracts/mevsim"
+	"go-bundles-go/valuation"
+	"math/big"
+	"math/rand"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAgentsWinSlots(t *testing.T) {
+	h := newSimHarness(t, 3)
+	low := h.newAgent(0, 1, 1e9)
+	high := h.newAgent(1, 1, 50e9)
+	alone := h.newAgent(2, 2, 1e9)
+	h.runAgents(low, high, alone)
+
+	const blocks = 3
+	startBlock := h.buildBlock(3)
+	for i := 1; i < blocks; i++ {
+		h.buildBlock(3)
+	}
+	endBlock := startBlock + blocks - 1
+
+	if value := h.slotValue(1); value != blocks {
+		t.Errorf("slot 1 value %d, expected %d", value, blocks)
+	}
+	if value := h.slotValue(2); value != blocks {
+		t.Errorf("slot 2 value %d, expected %d", value, blocks)
+	}
+
+	winner, block := h.lastWinner(1)
+	if winner != high.Signer.Address() || block != endBlock {
+		t.Errorf("slot 1 last winner %s at %d, expected %s at %d", winner.Hex(), block, high.Signer.Address().Hex(), endBlock)
+	}
+	winner, block = h.lastWinner(2)
+	if winner != alone.Signer.Address() || block != endBlock {
+		t.Errorf("slot 2 last winner %s at %d, expected %s at %d", winner.Hex(), block, alone.Signer.Address().Hex(), endBlock)
+	}
+
+	it, err := h.mevsim.FilterAuctioned(&bind.FilterOpts{Start: startBlock, End: &endBlock}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	wins := make(map[common.Address]int)
+	for it.Next() {
+		wins[it.Event.Bidder]++
+		if it.Event.TipPerGas.Sign() <= 0 {
+			t.Errorf("auction in block %d paid no tip", it.Event.Raw.BlockNumber)
+		}
+	}
+	if err := it.Error(); err != nil {
+		t.Fatal(err)
+	}
+	expectedWins := map[common.Address]int{high.Signer.Address(): blocks, alone.Signer.Address(): blocks}
+	for addr, expected := range expectedWins {
+		if wins[addr] != expected {
+			t.Errorf("%s won %d times, expected %d", addr.Hex(), wins[addr], expected)
+		}
+	}
+	if wins[low.Signer.Address()] != 0 {
+		t.Errorf("outbid agent won %d times", wins[low.Signer.Address()])
+	}
+}
+
+func TestAgentBidVariants(t *testing.T) {
+	h := newSimHarness(t, 5)
+	legacy := h.newAgent(0, 1, 1e9)
+	legacy.TxType = types.LegacyTxType
+	poisson, err := arrival.New(arrival.ModelPoisson, 200, arrival.Options{}, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy.Arrival = poisson
+
+	accessList := h.newAgent(1, 2, 1e9)
+	accessList.TxType = types.AccessListTxType
+	accessList.AccessListMode = AccessListStatic
+	accessList.LogAccessListGas = true
+
+	burn := h.newAgent(2, 3, 1e9)
+	burn.BurnGas = 100000
+	burn.PaddingBytes = 1000
+
+	callBundle := h.newAgent(3, 4, 1e9)
+	callBundle.GasEstimateMode = GasEstimateCallBundle
+
+	fixedGas := h.newAgent(4, 5, 1e9)
+	fixedGas.GasLimit = 200000
+	// zero value mode sends no access list
+	fixedGas.AccessListMode = ""
+
+	agents := []*BundleAgent{legacy, accessList, burn, callBundle, fixedGas}
+	h.runAgents(agents...)
+	block := h.buildBlock(len(agents))
+
+	for _, agent := range agents {
+		slot := agent.Slot.Int64()
+		if value := h.slotValue(slot); value != 1 {
+			t.Errorf("slot %d value %d, expected 1", slot, value)
+		}
+		winner, winBlock := h.lastWinner(slot)
+		if winner != agent.Signer.Address() || winBlock != block {
+			t.Errorf("slot %d last winner %s at %d, expected %s at %d", slot, winner.Hex(), winBlock, agent.Signer.Address().Hex(), block)
+		}
+	}
+}
+
+func TestPausedAgentDoesNotBid(t *testing.T) {
+	h := newSimHarness(t, 1)
+	agent := h.newAgent(0, 1, 1e9)
+	agent.SetPaused(true)
+	h.runAgents(agent)
+
+	deadline := time.Now().Add(10 * time.Second)
+	for agent.NextBidCost() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for agent to price its bid")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	header, err := h.backend.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bidders := h.relay.bidders(header.Number.Uint64() + 1); bidders != 0 {
+		t.Fatalf("paused agent sent bundles, bidders %d", bidders)
+	}
+	if agent.NextBidCost().Sign() <= 0 {
+		t.Errorf("next bid cost %s, expected positive", agent.NextBidCost().String())
+	}
+
+	agent.SetPaused(false)
+	block := h.buildBlock(1)
+	winner, winBlock := h.lastWinner(1)
+	if winner != crypto.PubkeyToAddress(h.searchers[0].PublicKey) || winBlock != block {
+		t.Errorf("last winner %s at %d after resume, expected agent at %d", winner.Hex(), winBlock, block)
+	}
+}
+
+func TestValuationCapsBidsAndTracksProfit(t *testing.T) {
+	h := newSimHarness(t, 2)
+	ledger := valuation.NewLedger()
+	const gasLimit = 100000
+
+	// valuation covers about 2 gwei tip, bids starting at 50 gwei are capped
+	capped := h.newAgent(0, 1, 50e9)
+	capped.GasLimit = gasLimit
+	capped.Valuations = ledger
+	high := h.newAgent(1, 1, 10e9)
+	high.GasLimit = gasLimit
+	high.Valuations = ledger
+	var err error
+	capped.Valuation, err = valuation.New(valuation.ModelFixed, gasLimit*3e9, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	high.Valuation, err = valuation.New(valuation.ModelFixed, 1e16, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.runAgents(capped, high)
+
+	balanceBefore, err := h.backend.BalanceAt(context.Background(), high.Signer.Address(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := h.buildBlock(2)
+	balanceAfter, err := h.backend.BalanceAt(context.Background(), high.Signer.Address(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.relay.mu.Lock()
+	for _, tx := range h.relay.bundles[block] {
+		from, err := types.Sender(h.relay.signer, tx)
+		if err != nil || from != capped.Signer.Address() {
+			continue
+		}
+		maxPaid := new(big.Int).Mul(tx.GasFeeCap(), new(big.Int).SetUint64(tx.Gas()))
+		if maxPaid.Cmp(big.NewInt(gasLimit*3e9)) > 0 {
+			t.Errorf("capped agent bid can pay %s, above its valuation", maxPaid.String())
+		}
+	}
+	h.relay.mu.Unlock()
+
+	tracker, err := NewInclusionTracker(h.backend, h.mevsimAddr, []*big.Int{big.NewInt(1)}, []common.Address{capped.Address(), high.Address()}, ledger, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tracker.processBlocks(context.Background(), block, block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profit, ok := tracker.Profit(high.Address())
+	if !ok {
+		t.Fatal("high valuation agent isn't tracked")
+	}
+	gasPaid := new(big.Int).Sub(balanceBefore, balanceAfter)
+	if profit.Wins != 1 || profit.GasPaid.Cmp(gasPaid) != 0 {
+		t.Errorf("high valuation agent won %d times paying %s, expected 1 win paying %s", profit.Wins, profit.GasPaid.String(), gasPaid.String())
+	}
+	if expected := new(big.Int).Sub(big.NewInt(1e16), gasPaid); profit.Profit.Cmp(expected) != 0 {
+		t.Errorf("profit %s, expected %s", profit.Profit.String(), expected.String())
+	}
+	if profit, _ := tracker.Profit(capped.Address()); profit.Wins != 0 {
+		t.Errorf("capped agent won %d times", profit.Wins)
+	}
+	stats := tracker.Stats()
+	if stats.Auctions != 1 || stats.Efficient != 1 || stats.Revenue.Sign() <= 0 {
+		t.Errorf("auction stats %+v, expected 1 efficient auction with revenue", stats)
+	}
+	if _, ok := tracker.Profit(common.Address{1}); ok {
+		t.Error("profit of untracked address")
+	}
+}
+
+func TestBanditAgentLearnsFromInclusion(t *testing.T) {
+	h := newSimHarness(t, 1)
+	agent := h.newAgent(0, 1, 1e9)
+	arms := []BanditArm{{Start: big.NewInt(1e9), Increment: big.NewInt(0)}}
+	statePath := filepath.Join(t.TempDir(), "bandit.json")
+	bandit, err := NewBanditStrategy(arms, big.NewInt(10e9), 1, statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent.Strategy = bandit
+	h.runAgents(agent)
+
+	h.buildBlock(1)
+	// outcome of the first block is learned and saved before bidding for the second one
+	h.buildBlock(1)
+	learned, err := NewBanditStrategy(arms, big.NewInt(10e9), 1, statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if learned.pulls[0] == 0 || learned.rewards[0] <= 0 {
+		t.Errorf("bandit learned %d outcomes with reward %f, expected a won slot", learned.pulls[0], learned.rewards[0])
+	}
+}
+
+func TestMarginAgentsEscalate(t *testing.T) {
+	h := newSimHarness(t, 2)
+	observations := NewObservations()
+	margin := MarginStrategy{Start: big.NewInt(1e9), Margin: big.NewInt(1e9)}
+	first := h.newAgent(0, 1, 1e9)
+	first.Strategy = margin
+	first.Observations = observations
+	second := h.newAgent(1, 1, 1e9)
+	second.Strategy = margin
+	second.Observations = observations
+	h.runAgents(first, second)
+
+	tracker, err := NewInclusionTracker(h.backend, h.mevsimAddr, []*big.Int{big.NewInt(1)}, nil, nil, observations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		lastWinner common.Address
+		lastTip    int64
+	)
+	for i := 0; i < 4; i++ {
+		block := h.buildBlock(2)
+		err = tracker.processBlocks(context.Background(), block, block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tip, _, winner := observations.LastWinner(big.NewInt(1))
+		// loser outbids the winner by margin, winner holds its price.
+		// Effective tip can be a few wei lower when base fee rises over agent's estimate.
+		if tip == nil || tip.Int64() < lastTip+0.9e9 || tip.Int64() > lastTip+1e9 || winner == lastWinner {
+			t.Fatalf("block %d won by %s with tip %v, expected rival of %s with 1 gwei over %d", block, winner.Hex(), tip, lastWinner.Hex(), lastTip)
+		}
+		lastWinner, lastTip = winner, tip.Int64()
+		// give agents time to bid with the new observation
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestReorgRevertsWinsAndAgentRebids(t *testing.T) {
+	h := newSimHarness(t, 1)
+	agent := h.newAgent(0, 1, 1e9)
+	h.runAgents(agent)
+	observations := NewObservations()
+	tracker, err := NewInclusionTracker(h.backend, h.mevsimAddr, []*big.Int{big.NewInt(1)}, []common.Address{agent.Address()}, nil, observations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	// accessors are read while blocks are processed and reverted
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			_, _ = tracker.Profit(agent.Address())
+			_ = tracker.Stats()
+		}
+	}()
+	defer readers.Wait()
+	defer close(done)
+
+	parent, err := h.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := h.buildBlock(1)
+	err = tracker.processBlocks(ctx, block, block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profit, _ := tracker.Profit(agent.Address()); profit.Wins != 1 {
+		t.Fatalf("agent won %d times before reorg, expected 1", profit.Wins)
+	}
+
+	// side chain without the win becomes canonical once it's longer
+	err = h.backend.Fork(ctx, parent.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.backend.Commit()
+	h.backend.Commit()
+	head, err := h.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Number.Uint64() != block+1 || h.slotValue(1) != 0 {
+		t.Fatalf("head %d with slot value %d, expected reorg to %d without the win", head.Number, h.slotValue(1), block+1)
+	}
+
+	fork, err := tracker.forkPoint(ctx, head, block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fork != block-1 {
+		t.Fatalf("fork at %d, expected %d", fork, block-1)
+	}
+	if reverted := tracker.revert(fork+1, block); reverted != 1 {
+		t.Errorf("%d wins reverted, expected 1", reverted)
+	}
+	if profit, _ := tracker.Profit(agent.Address()); profit.Wins != 0 || profit.GasPaid.Sign() != 0 {
+		t.Errorf("agent has %d wins paying %s after reorg, expected none", profit.Wins, profit.GasPaid.String())
+	}
+	if tip, _, _ := observations.LastWinner(big.NewInt(1)); tip != nil {
+		t.Error("win of replaced block is still observed")
+	}
+
+	// agent bids with slot value of the new chain
+	rebid := h.buildBlock(1)
+	if value := h.slotValue(1); value != 1 {
+		t.Errorf("slot value %d after reorg, expected 1", value)
+	}
+	if winner, winBlock := h.lastWinner(1); winner != agent.Address() || winBlock != rebid {
+		t.Errorf("slot won by %s in block %d, expected agent in %d", winner.Hex(), winBlock, rebid)
+	}
+	err = tracker.processBlocks(ctx, fork+1, rebid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profit, _ := tracker.Profit(agent.Address()); profit.Wins != 1 {
+		t.Errorf("agent won %d times after reorg, expected 1", profit.Wins)
+	}
+}
+
+func TestStaticAccessListMatchesTracedAccessList(t *testing.T) {
+	h := newSimHarness(t, 1)
+	agent := h.newAgent(0, 3, 1e9)
+	mevsimAbi, err := mevsim.MevSimMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest := h.backend.Blockchain().CurrentHeader()
+	data, err := agent.auctionCallData(mevsimAbi, big.NewInt(0), latest.Number.Uint64()+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	static := mevsim.StaticAuctionAccessList(h.mevsimAddr, agent.Slot, latest.Coinbase)
+
+	// access list traced the way eth_createAccessList does, on the next block
+	next := types.CopyHeader(latest)
+	next.Number.Add(next.Number, big.NewInt(1))
+	next.ParentHash = latest.Hash()
+	config := h.backend.Blockchain().Config()
+	rules := config.Rules(next.Number, false)
+	tracer := logger.NewAccessListTracer(nil, agent.Address(), h.mevsimAddr, vm.ActivePrecompiles(rules))
+	stateDB, err := h.backend.Blockchain().State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gasPrice := new(big.Int).Mul(next.BaseFee, big.NewInt(2))
+	msg := types.NewMessage(agent.Address(), &h.mevsimAddr, 0, big.NewInt(0), 1000000, gasPrice, gasPrice, big.NewInt(0), data, nil, true)
+	evm := vm.NewEVM(core.NewEVMBlockContext(next, h.backend.Blockchain(), nil), core.NewEVMTxContext(msg), stateDB, config, vm.Config{Debug: true, Tracer: tracer})
+	result, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(msg.Gas()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Failed() {
+		t.Fatalf("auction call failed: %v", result.Err)
+	}
+
+	var traced []common.Hash
+	for _, tuple := range tracer.AccessList() {
+		if tuple.Address == h.mevsimAddr {
+			traced = tuple.StorageKeys
+		}
+	}
+	staticKeys := make(map[common.Hash]bool)
+	for _, key := range static[0].StorageKeys {
+		staticKeys[key] = true
+	}
+	if len(traced) != len(staticKeys) {
+		t.Errorf("auction touches %d MevSim storage keys, static access list has %d", len(traced), len(staticKeys))
+	}
+	for _, key := range traced {
+		if !staticKeys[key] {
+			t.Errorf("storage key %s touched by auction is missing in static access list", key.Hex())
+		}
+	}
+
+	// every listed key saves gas, a missing one would be a cold access
+	msgWith := ethereum.CallMsg{From: agent.Address(), To: &h.mevsimAddr, Data: data, AccessList: static}
+	gasWith, err := h.backend.EstimateGas(context.Background(), msgWith)
+	if err != nil {
+		t.Fatal(err)
+	}
+	partial := types.AccessList{{Address: h.mevsimAddr, StorageKeys: static[0].StorageKeys[:1]}, static[1]}
+	msgPartial := ethereum.CallMsg{From: agent.Address(), To: &h.mevsimAddr, Data: data, AccessList: partial}
+	gasPartial, err := h.backend.EstimateGas(context.Background(), msgPartial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gasWith >= gasPartial {
+		t.Errorf("gas %d with static access list, %d without winner key", gasWith, gasPartial)
+	}
+}
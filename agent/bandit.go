@@ -0,0 +1,223 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+)
+
+// Outcome is result of agent's bids for a slot, read from Auctioned logs of the target block
+type Outcome struct {
+	TargetBlock uint64
+	Won         bool
+	// tip per gas paid by the winning bid, nil if lost
+	TipPerGas *big.Int
+	// valuation and its tip ceiling in the slot, nil if agent has no valuation
+	Valuation      *big.Int
+	MaxEffGasPrice *big.Int
+}
+
+// Learner is strategy that adapts to outcomes of its past slots
+type Learner interface {
+	Strategy
+	Outcome(outcome Outcome)
+}
+
+// BanditArm is one starting bid and increment the bandit can choose
+type BanditArm struct {
+	Start     *big.Int
+	Increment *big.Int
+}
+
+// BanditStrategy chooses starting bid and increment of every slot with UCB1 over arms.
+// Reward of a won slot is its profit relative to value per gas, tip ceiling from valuation or value,
+// so both losing and overpaying are penalized. Lost slots have zero reward.
+// It is not safe for concurrent use, every agent needs its own.
+type BanditStrategy struct {
+	arms []BanditArm
+	// tip per gas worth paying to win the slot, used if agent has no valuation
+	value *big.Int
+	// weight of exploration term of UCB1
+	exploration float64
+	// optional file learned state is loaded from and saved to after every outcome
+	statePath string
+
+	pulls   []uint64
+	rewards []float64
+	// arm chosen for target block
+	slots map[uint64]int
+}
+
+// NewBanditStrategy creates bandit over arms, value is tip per gas worth paying to win the slot if agent has no valuation.
+// Learned state is loaded from statePath if it exists and saved there after every outcome, empty path disables it.
+func NewBanditStrategy(arms []BanditArm, value *big.Int, exploration float64, statePath string) (*BanditStrategy, error) {
+	if len(arms) == 0 {
+		return nil, fmt.Errorf("bandit needs at least one arm")
+	}
+	if value == nil || value.Sign() <= 0 {
+		return nil, fmt.Errorf("bandit value must be > 0")
+	}
+	b := &BanditStrategy{
+		arms:        arms,
+		value:       value,
+		exploration: exploration,
+		statePath:   statePath,
+		pulls:       make([]uint64, len(arms)),
+		rewards:     make([]float64, len(arms)),
+		slots:       make(map[uint64]int),
+	}
+	if statePath != "" {
+		err := b.load()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func (b *BanditStrategy) Bid(state SlotState) *big.Int {
+	arm, ok := b.slots[state.TargetBlock]
+	if !ok {
+		arm = b.choose()
+		b.slots[state.TargetBlock] = arm
+	}
+	price := new(big.Int).Mul(b.arms[arm].Increment, big.NewInt(int64(state.Bids)))
+	return price.Add(price, b.arms[arm].Start)
+}
+
+// MaxBid returns the highest bid of any arm
+func (b *BanditStrategy) MaxBid(bids int) *big.Int {
+	if bids < 1 {
+		bids = 1
+	}
+	var max *big.Int
+	for _, arm := range b.arms {
+		bid := LinearStrategy{Start: arm.Start, Increment: arm.Increment}.MaxBid(bids)
+		if max == nil || bid.Cmp(max) > 0 {
+			max = bid
+		}
+	}
+	return max
+}
+
+// choose returns untried arm or arm with the highest upper confidence bound
+func (b *BanditStrategy) choose() int {
+	var total uint64
+	for arm, pulls := range b.pulls {
+		if pulls == 0 {
+			return arm
+		}
+		total += pulls
+	}
+	best, bestBound := 0, math.Inf(-1)
+	for arm, pulls := range b.pulls {
+		bound := b.rewards[arm]/float64(pulls) + b.exploration*math.Sqrt(2*math.Log(float64(total))/float64(pulls))
+		if bound > bestBound {
+			best, bestBound = arm, bound
+		}
+	}
+	return best
+}
+
+func (b *BanditStrategy) Outcome(outcome Outcome) {
+	arm, ok := b.slots[outcome.TargetBlock]
+	if !ok {
+		return
+	}
+	for block := range b.slots {
+		if block <= outcome.TargetBlock {
+			delete(b.slots, block)
+		}
+	}
+
+	reward := 0.0
+	if outcome.Won {
+		value := b.value
+		if outcome.MaxEffGasPrice != nil {
+			value = outcome.MaxEffGasPrice
+		}
+		if value.Sign() > 0 {
+			profit := new(big.Float).SetInt(new(big.Int).Sub(value, outcome.TipPerGas))
+			reward, _ = profit.Quo(profit, new(big.Float).SetInt(value)).Float64()
+		}
+		// bounded rewards keep confidence bounds meaningful
+		reward = math.Max(-1, math.Min(1, reward))
+	}
+	b.pulls[arm]++
+	b.rewards[arm] += reward
+	fmt.Println("bandit outcome", "targetBlock", outcome.TargetBlock, "won", outcome.Won, "reward", reward,
+		"arm", arm, "armMeanReward", b.rewards[arm]/float64(b.pulls[arm]), "armPulls", b.pulls[arm])
+
+	if b.statePath != "" {
+		err := b.save()
+		if err != nil {
+			fmt.Println("error saving bandit state", err)
+		}
+	}
+}
+
+type banditArmState struct {
+	Start     string  `json:"start"`
+	Increment string  `json:"increment"`
+	Pulls     uint64  `json:"pulls"`
+	RewardSum float64 `json:"rewardSum"`
+}
+
+// load restores pulls and rewards of arms found in the state file, other arms start untried
+func (b *BanditStrategy) load() error {
+	data, err := os.ReadFile(b.statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var states []banditArmState
+	err = json.Unmarshal(data, &states)
+	if err != nil {
+		return fmt.Errorf("bandit state %s: %w", b.statePath, err)
+	}
+	for _, state := range states {
+		for arm := range b.arms {
+			if b.arms[arm].Start.String() == state.Start && b.arms[arm].Increment.String() == state.Increment {
+				b.pulls[arm] = state.Pulls
+				b.rewards[arm] = state.RewardSum
+			}
+		}
+	}
+	return nil
+}
+
+func (b *BanditStrategy) save() error {
+	states := make([]banditArmState, len(b.arms))
+	for arm := range b.arms {
+		states[arm] = banditArmState{
+			Start:     b.arms[arm].Start.String(),
+			Increment: b.arms[arm].Increment.String(),
+			Pulls:     b.pulls[arm],
+			RewardSum: b.rewards[arm],
+		}
+	}
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return err
+	}
+	// written to temporary file first so interrupted run doesn't leave broken state
+	tmp, err := os.CreateTemp(filepath.Dir(b.statePath), filepath.Base(b.statePath)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), b.statePath)
+}
@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+)
+
+// playBandit runs bandit in rounds against competitor bidding competitorTip every slot
+func playBandit(bandit *BanditStrategy, firstBlock uint64, rounds int, competitorTip int64) {
+	for block := firstBlock; block < firstBlock+uint64(rounds); block++ {
+		tip := bandit.Bid(SlotState{TargetBlock: block})
+		outcome := Outcome{TargetBlock: block}
+		if tip.Int64() > competitorTip {
+			outcome.Won = true
+			outcome.TipPerGas = tip
+		}
+		bandit.Outcome(outcome)
+	}
+}
+
+func TestBanditLearnsCheapestWinningBid(t *testing.T) {
+	arms := []BanditArm{
+		{Start: big.NewInt(1e9), Increment: big.NewInt(0)},
+		{Start: big.NewInt(5e9), Increment: big.NewInt(0)},
+		{Start: big.NewInt(9e9), Increment: big.NewInt(0)},
+	}
+	statePath := filepath.Join(t.TempDir(), "bandit.json")
+	bandit, err := NewBanditStrategy(arms, big.NewInt(10e9), 0.2, statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	playBandit(bandit, 1, 300, 4e9)
+	// 1 gwei loses, 9 gwei wins but overpays
+	if bandit.pulls[1] < 250 {
+		t.Errorf("arms pulled %v times, expected 5 gwei arm to dominate", bandit.pulls)
+	}
+
+	restored, err := NewBanditStrategy(arms, big.NewInt(10e9), 0.2, statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for arm := range arms {
+		if restored.pulls[arm] != bandit.pulls[arm] || restored.rewards[arm] != bandit.rewards[arm] {
+			t.Fatalf("restored state %v %v, expected %v %v", restored.pulls, restored.rewards, bandit.pulls, bandit.rewards)
+		}
+	}
+	if tip := restored.Bid(SlotState{TargetBlock: 1000}); tip.Int64() != 5e9 {
+		t.Errorf("restored bandit bids %s, expected learned 5 gwei", tip.String())
+	}
+}
@@ -0,0 +1,377 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"go-bundles-go/chain"
+	"go-bundles-go/contracts/mevsim"
+	"go-bundles-go/valuation"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// TrackerBackend is the part of the node used by InclusionTracker
+type TrackerBackend interface {
+	bind.ContractFilterer
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+// Profit is outcome of our agent's wins, Profit is Value minus GasPaid and CoinbasePaid
+type Profit struct {
+	Wins         uint64
+	Value        *big.Int
+	GasPaid      *big.Int
+	CoinbasePaid *big.Int
+	Profit       *big.Int
+}
+
+// AuctionStats measures tracked auctions with known valuations
+type AuctionStats struct {
+	Auctions uint64
+	// auctions won by the bidder with the highest valuation
+	Efficient uint64
+	// tips and coinbase payments of winners
+	Revenue *big.Int
+	// sums of winner valuations and the highest valuations, their ratio is allocative efficiency
+	WinnerValue *big.Int
+	MaxValue    *big.Int
+}
+
+// InclusionTracker reports winners of tracked slots using MevSim Auctioned logs
+type InclusionTracker struct {
+	client TrackerBackend
+	mevsim *mevsim.MevSimFilterer
+	slots  []*big.Int
+	// optional valuations drawn by agents
+	valuations *valuation.Ledger
+	// optional observations wins are reported to
+	observations *Observations
+
+	// guards profits and stats read by accessors while Run updates them
+	mu sync.Mutex
+	// outcome of our agents by address
+	profits map[common.Address]*Profit
+	stats   AuctionStats
+	// recently processed blocks, reverted if a reorg replaces them
+	blocks map[uint64]*trackedBlock
+}
+
+// trackedHistory is number of processed blocks InclusionTracker can revert on reorg
+const trackedHistory = 64
+
+// trackedBlock is processed block with what its wins added to profits and stats
+type trackedBlock struct {
+	hash    common.Hash
+	profits map[common.Address]*Profit
+	stats   AuctionStats
+}
+
+// auctionEvent is Auctioned event with gas used by its tx
+type auctionEvent struct {
+	*mevsim.MevSimAuctioned
+	gasUsed *big.Int
+}
+
+func NewInclusionTracker(client TrackerBackend, mevsimAddr common.Address, slots []*big.Int, agents []common.Address, valuations *valuation.Ledger, observations *Observations) (*InclusionTracker, error) {
+	filterer, err := mevsim.NewMevSimFilterer(mevsimAddr, client)
+	if err != nil {
+		return nil, err
+	}
+	profits := make(map[common.Address]*Profit)
+	for _, agent := range agents {
+		profits[agent] = newProfit()
+	}
+	return &InclusionTracker{
+		client:       client,
+		mevsim:       filterer,
+		slots:        slots,
+		valuations:   valuations,
+		observations: observations,
+		profits:      profits,
+		stats:        newAuctionStats(),
+		blocks:       make(map[uint64]*trackedBlock),
+	}, nil
+}
+
+func (t *InclusionTracker) Run(ctx context.Context) error {
+	var lastBlockNumber uint64
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		header, err := t.client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			continue
+		}
+		blockNumber := header.Number.Uint64()
+		if lastBlockNumber == 0 {
+			lastBlockNumber = blockNumber
+			continue
+		}
+
+		fork, err := t.forkPoint(ctx, header, lastBlockNumber)
+		if err != nil {
+			fmt.Println("error checking for reorg", err)
+			continue
+		}
+		if fork < lastBlockNumber {
+			wins := t.revert(fork+1, lastBlockNumber)
+			fmt.Println("reorg detected", "depth", lastBlockNumber-fork, "fork", fork, "oldBlock", lastBlockNumber,
+				"newBlock", blockNumber, "newHash", header.Hash().Hex(), "revertedWins", wins)
+			lastBlockNumber = fork
+		}
+		if blockNumber <= lastBlockNumber {
+			continue
+		}
+
+		err = t.processBlocks(ctx, lastBlockNumber+1, blockNumber)
+		if err != nil {
+			fmt.Println("error filtering auction logs", err)
+			continue
+		}
+		lastBlockNumber = blockNumber
+	}
+}
+
+// Profit returns outcome of agent's wins so far, false if agent isn't tracked
+func (t *InclusionTracker) Profit(agent common.Address) (Profit, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	profit, ok := t.profits[agent]
+	if !ok {
+		return Profit{}, false
+	}
+	result := *newProfit()
+	result.add(profit)
+	return result, true
+}
+
+// Stats returns measures of auctions so far
+func (t *InclusionTracker) Stats() AuctionStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats := newAuctionStats()
+	stats.add(&t.stats)
+	return stats
+}
+
+// forkPoint returns the highest processed block up to last that is still in the chain of head,
+// last if there was no reorg. Blocks older than tracked ones are assumed to be final.
+func (t *InclusionTracker) forkPoint(ctx context.Context, head *types.Header, last uint64) (uint64, error) {
+	headNumber := head.Number.Uint64()
+	for n := last; n > 0; n-- {
+		block, ok := t.blocks[n]
+		if !ok {
+			return n, nil
+		}
+		if n > headNumber {
+			continue
+		}
+		var hash common.Hash
+		switch n {
+		case headNumber:
+			hash = head.Hash()
+		case headNumber - 1:
+			hash = head.ParentHash
+		default:
+			header, err := t.client.HeaderByNumber(ctx, new(big.Int).SetUint64(n))
+			if err != nil {
+				return 0, err
+			}
+			hash = header.Hash()
+		}
+		if hash == block.hash {
+			return n, nil
+		}
+	}
+	return 0, nil
+}
+
+// revert takes back wins of processed blocks from..to replaced by reorg and returns their number
+func (t *InclusionTracker) revert(from, to uint64) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var wins uint64
+	for n := from; n <= to; n++ {
+		block, ok := t.blocks[n]
+		if !ok {
+			continue
+		}
+		for bidder, profit := range block.profits {
+			t.profits[bidder].sub(profit)
+			wins += profit.Wins
+		}
+		t.stats.sub(&block.stats)
+		delete(t.blocks, n)
+	}
+	t.observations.ForgetWinners(from)
+	return wins
+}
+
+func (t *InclusionTracker) processBlocks(ctx context.Context, from, to uint64) error {
+	// blocks are read before logs, logs of a block replaced in between are detected by hash
+	processed := make(map[uint64]*trackedBlock)
+	baseFees := make(map[uint64]*big.Int)
+	for n := from; n <= to; n++ {
+		header, err := t.client.HeaderByNumber(ctx, new(big.Int).SetUint64(n))
+		if err != nil {
+			return err
+		}
+		processed[n] = &trackedBlock{hash: header.Hash(), profits: make(map[common.Address]*Profit), stats: newAuctionStats()}
+		// chains without EIP-1559 have no base fee, their gas price is all tip
+		baseFees[n] = header.BaseFee
+		if baseFees[n] == nil {
+			baseFees[n] = big.NewInt(0)
+		}
+	}
+
+	it, err := t.mevsim.FilterAuctioned(&bind.FilterOpts{Start: from, End: &to, Context: ctx}, t.slots, nil)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	var events []auctionEvent
+	for it.Next() {
+		event := it.Event
+		if event.Raw.BlockHash != processed[event.Raw.BlockNumber].hash {
+			return fmt.Errorf("block %d replaced while processing", event.Raw.BlockNumber)
+		}
+		receipt, err := t.client.TransactionReceipt(ctx, event.Raw.TxHash)
+		if err != nil {
+			return err
+		}
+		events = append(events, auctionEvent{MevSimAuctioned: event, gasUsed: new(big.Int).SetUint64(receipt.GasUsed)})
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	auctions := t.stats.Auctions
+	for _, event := range events {
+		block := event.Raw.BlockNumber
+		tracked := processed[block]
+		t.observations.RecordWinner(event.Slot, block, event.Bidder, event.TipPerGas)
+
+		gasPaid := new(big.Int).Add(baseFees[block], event.TipPerGas)
+		gasPaid.Mul(gasPaid, event.gasUsed)
+		revenue := new(big.Int).Mul(event.TipPerGas, event.gasUsed)
+		revenue.Add(revenue, event.CoinbasePaid)
+
+		var value, maxValue *big.Int
+		if t.valuations != nil {
+			value = t.valuations.Value(block, event.Slot, event.Bidder)
+			maxValue, _ = t.valuations.Max(block, event.Slot)
+		}
+		if maxValue != nil {
+			auction := AuctionStats{Auctions: 1, Revenue: revenue, WinnerValue: new(big.Int), MaxValue: maxValue}
+			if value != nil {
+				auction.WinnerValue = value
+				if value.Cmp(maxValue) == 0 {
+					auction.Efficient = 1
+				}
+			}
+			t.stats.add(&auction)
+			tracked.stats.add(&auction)
+		}
+
+		profit, ours := t.profits[event.Bidder]
+		if !ours {
+			fmt.Println("slot won", "block", block, "slot", event.Slot, "bidder", event.Bidder.Hex(), "ours", ours,
+				"tipPerGas(gwei)", chain.WeiToUnit(event.TipPerGas, 1e9), "coinbasePaid(eth)", chain.WeiToUnit(event.CoinbasePaid, 1e18))
+			continue
+		}
+		win := &Profit{Wins: 1, Value: new(big.Int), GasPaid: gasPaid, CoinbasePaid: event.CoinbasePaid}
+		win.Profit = new(big.Int).Neg(gasPaid)
+		win.Profit.Sub(win.Profit, event.CoinbasePaid)
+		if value != nil {
+			win.Value = value
+			win.Profit.Add(win.Profit, value)
+		}
+		profit.add(win)
+		if tracked.profits[event.Bidder] == nil {
+			tracked.profits[event.Bidder] = newProfit()
+		}
+		tracked.profits[event.Bidder].add(win)
+		fmt.Println("slot won", "block", block, "slot", event.Slot, "bidder", event.Bidder.Hex(), "ours", ours,
+			"tipPerGas(gwei)", chain.WeiToUnit(event.TipPerGas, 1e9), "coinbasePaid(eth)", chain.WeiToUnit(event.CoinbasePaid, 1e18), "bidderWins", profit.Wins,
+			"gasPaid(eth)", chain.WeiToUnit(gasPaid, 1e18), "profit(eth)", chain.WeiToUnit(win.Profit, 1e18), "bidderProfit(eth)", chain.WeiToUnit(profit.Profit, 1e18))
+	}
+
+	for n, block := range processed {
+		t.blocks[n] = block
+	}
+	for n := range t.blocks {
+		if n+trackedHistory <= to {
+			delete(t.blocks, n)
+		}
+	}
+
+	if t.valuations != nil {
+		// valuations of tracked blocks are kept for reprocessing them after reorg
+		if to+1 > trackedHistory {
+			t.valuations.Prune(to + 1 - trackedHistory)
+		}
+		if t.stats.Auctions > auctions {
+			efficiency := 0.0
+			if t.stats.MaxValue.Sign() > 0 {
+				efficiency, _ = new(big.Float).Quo(new(big.Float).SetInt(t.stats.WinnerValue), new(big.Float).SetInt(t.stats.MaxValue)).Float64()
+			}
+			fmt.Println("auction stats", "auctions", t.stats.Auctions, "efficient", t.stats.Efficient,
+				"revenue(eth)", chain.WeiToUnit(t.stats.Revenue, 1e18), "efficiency", efficiency)
+		}
+	}
+	return nil
+}
+
+func newProfit() *Profit {
+	return &Profit{Value: new(big.Int), GasPaid: new(big.Int), CoinbasePaid: new(big.Int), Profit: new(big.Int)}
+}
+
+func (p *Profit) add(q *Profit) {
+	p.Wins += q.Wins
+	p.Value.Add(p.Value, q.Value)
+	p.GasPaid.Add(p.GasPaid, q.GasPaid)
+	p.CoinbasePaid.Add(p.CoinbasePaid, q.CoinbasePaid)
+	p.Profit.Add(p.Profit, q.Profit)
+}
+
+func (p *Profit) sub(q *Profit) {
+	p.Wins -= q.Wins
+	p.Value.Sub(p.Value, q.Value)
+	p.GasPaid.Sub(p.GasPaid, q.GasPaid)
+	p.CoinbasePaid.Sub(p.CoinbasePaid, q.CoinbasePaid)
+	p.Profit.Sub(p.Profit, q.Profit)
+}
+
+func newAuctionStats() AuctionStats {
+	return AuctionStats{Revenue: new(big.Int), WinnerValue: new(big.Int), MaxValue: new(big.Int)}
+}
+
+func (s *AuctionStats) add(q *AuctionStats) {
+	s.Auctions += q.Auctions
+	s.Efficient += q.Efficient
+	s.Revenue.Add(s.Revenue, q.Revenue)
+	s.WinnerValue.Add(s.WinnerValue, q.WinnerValue)
+	s.MaxValue.Add(s.MaxValue, q.MaxValue)
+}
+
+func (s *AuctionStats) sub(q *AuctionStats) {
+	s.Auctions -= q.Auctions
+	s.Efficient -= q.Efficient
+	s.Revenue.Sub(s.Revenue, q.Revenue)
+	s.WinnerValue.Sub(s.WinnerValue, q.WinnerValue)
+	s.MaxValue.Sub(s.MaxValue, q.MaxValue)
+}
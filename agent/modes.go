@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"fmt"
+)
+
+const (
+	GasEstimateRPC        = "estimate"    // eth_estimateGas on pending state
+	GasEstimateCallBundle = "call-bundle" // eth_callBundle on the relay
+
+	// gas limit of the tx simulated with eth_callBundle
+	CallBundleGasLimit = uint64(1000000)
+)
+
+func ParseGasEstimateMode(s string) (string, error) {
+	switch s {
+	case GasEstimateRPC, GasEstimateCallBundle:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown gas estimate mode %s, expected estimate or call-bundle", s)
+	}
+}
+
+const (
+	AccessListNone   = "none"
+	AccessListStatic = "static" // built from the slot number and coinbase of the head
+	AccessListRPC    = "rpc"    // generated by eth_createAccessList
+)
+
+func ParseAccessListMode(s string) (string, error) {
+	switch s {
+	case AccessListNone, AccessListStatic, AccessListRPC:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown access list mode %s, expected none, static or rpc", s)
+	}
+}
+
+const (
+	StrategyLinear = "linear" // starting bid raised by increment every bid
+	StrategyBandit = "bandit" // starting bid and increment learned across blocks
+	StrategyMargin = "margin" // margin above the best observed rival bid
+)
+
+func ParseStrategy(s string) (string, error) {
+	switch s {
+	case StrategyLinear, StrategyBandit, StrategyMargin:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown strategy %s, expected linear, bandit or margin", s)
+	}
+}
@@ -0,0 +1,228 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"go-bundles-go/contracts/mevsim"
+	"math/big"
+	"sync"
+	"time"
+)
+
+type observedWinner struct {
+	block  uint64
+	bidder common.Address
+	tip    *big.Int
+}
+
+type pendingAuction struct {
+	slot        string
+	targetBlock uint64
+}
+
+// Observations collects bids seen for slots: wins reported by InclusionTracker and pending bids seen by MempoolWatcher.
+// It's shared by agents, each of them reads bids of its rivals.
+type Observations struct {
+	mu      sync.Mutex
+	winners map[string]observedWinner
+	// the highest pending tip of every sender
+	pending map[pendingAuction]map[common.Address]*big.Int
+}
+
+func NewObservations() *Observations {
+	return &Observations{
+		winners: make(map[string]observedWinner),
+		pending: make(map[pendingAuction]map[common.Address]*big.Int),
+	}
+}
+
+// RecordWinner stores win of slot in block, older wins are ignored. Nil observations ignore it.
+func (o *Observations) RecordWinner(slot *big.Int, block uint64, bidder common.Address, tip *big.Int) {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if last, ok := o.winners[slot.String()]; ok && last.block > block {
+		return
+	}
+	o.winners[slot.String()] = observedWinner{block: block, bidder: bidder, tip: tip}
+}
+
+// ForgetWinners drops wins in blocks from block on, e.g. blocks replaced by a reorg. Nil observations ignore it.
+func (o *Observations) ForgetWinners(block uint64) {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for slot, winner := range o.winners {
+		if winner.block >= block {
+			delete(o.winners, slot)
+		}
+	}
+}
+
+// LastWinner returns tip, block and bidder of the last win of slot, nil tip if no win was seen
+func (o *Observations) LastWinner(slot *big.Int) (*big.Int, uint64, common.Address) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	winner, ok := o.winners[slot.String()]
+	if !ok {
+		return nil, 0, common.Address{}
+	}
+	return winner.tip, winner.block, winner.bidder
+}
+
+// RecordPending stores pending bid of sender for slot in targetBlock
+func (o *Observations) RecordPending(slot *big.Int, targetBlock uint64, sender common.Address, tip *big.Int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	key := pendingAuction{slot: slot.String(), targetBlock: targetBlock}
+	if o.pending[key] == nil {
+		o.pending[key] = make(map[common.Address]*big.Int)
+	}
+	if best := o.pending[key][sender]; best == nil || tip.Cmp(best) > 0 {
+		o.pending[key][sender] = tip
+	}
+}
+
+// PendingBest returns the highest pending tip for slot in targetBlock of senders other than exclude, nil if none
+func (o *Observations) PendingBest(slot *big.Int, targetBlock uint64, exclude common.Address) *big.Int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var best *big.Int
+	for sender, tip := range o.pending[pendingAuction{slot: slot.String(), targetBlock: targetBlock}] {
+		if sender != exclude && (best == nil || tip.Cmp(best) > 0) {
+			best = tip
+		}
+	}
+	return best
+}
+
+// prunePending drops pending bids for blocks up to block
+func (o *Observations) prunePending(block uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for key := range o.pending {
+		if key.targetBlock <= block {
+			delete(o.pending, key)
+		}
+	}
+}
+
+// MempoolWatcher records public auction txs from node mempool as pending bids.
+// Bundles sent to relays are private, so it only sees rivals that also broadcast their bids.
+type MempoolWatcher struct {
+	rpcClient    *gethrpc.Client
+	client       *ethclient.Client
+	signer       types.Signer
+	mevsimAddr   common.Address
+	observations *Observations
+
+	auctionIDs [][]byte
+}
+
+// NewMempoolWatcher creates watcher of node at url that supports subscriptions, e.g. websocket
+func NewMempoolWatcher(url string, chainID *big.Int, mevsimAddr common.Address, observations *Observations) (*MempoolWatcher, error) {
+	rpcClient, err := gethrpc.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+	mevsimAbi, err := mevsim.MevSimMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	return &MempoolWatcher{
+		rpcClient:    rpcClient,
+		client:       ethclient.NewClient(rpcClient),
+		signer:       types.LatestSignerForChainID(chainID),
+		mevsimAddr:   mevsimAddr,
+		observations: observations,
+		auctionIDs:   [][]byte{mevsimAbi.Methods["auction"].ID, mevsimAbi.Methods["auctionWithBurn"].ID},
+	}, nil
+}
+
+// Run records pending auction txs until ctx is canceled or subscription fails
+func (w *MempoolWatcher) Run(ctx context.Context) error {
+	hashes := make(chan common.Hash, 1024)
+	sub, err := gethclient.New(w.rpcClient).SubscribePendingTransactions(ctx, hashes)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	var (
+		baseFee     *big.Int
+		headBlock   uint64
+		headUpdated time.Time
+	)
+	for {
+		var hash common.Hash
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-sub.Err():
+			return err
+		case hash = <-hashes:
+		}
+
+		// tips are measured against base fee of the head block, refreshed at most every second
+		if time.Since(headUpdated) > time.Second {
+			header, err := w.client.HeaderByNumber(ctx, nil)
+			if err != nil {
+				fmt.Println("error getting block", err)
+				continue
+			}
+			baseFee, headUpdated = header.BaseFee, time.Now()
+			if header.Number.Uint64() > headBlock {
+				headBlock = header.Number.Uint64()
+				w.observations.prunePending(headBlock)
+			}
+		}
+
+		tx, _, err := w.client.TransactionByHash(ctx, hash)
+		if err != nil {
+			// tx can be already mined or dropped
+			continue
+		}
+		w.record(tx, baseFee)
+	}
+}
+
+// record stores tx as pending bid if it calls auction of MevSim
+func (w *MempoolWatcher) record(tx *types.Transaction, baseFee *big.Int) {
+	if tx.To() == nil || *tx.To() != w.mevsimAddr || len(tx.Data()) < 4+3*32 {
+		return
+	}
+	data := tx.Data()
+	auction := false
+	for _, id := range w.auctionIDs {
+		auction = auction || bytes.Equal(data[:4], id)
+	}
+	if !auction {
+		return
+	}
+	sender, err := types.Sender(w.signer, tx)
+	if err != nil {
+		return
+	}
+	tip, err := tx.EffectiveGasTip(baseFee)
+	if err != nil {
+		// fee cap below base fee, bid can't be included
+		return
+	}
+	// auction(slot, value, target_block), auctionWithBurn starts with the same arguments
+	slot := new(big.Int).SetBytes(data[4:36])
+	targetBlock := new(big.Int).SetBytes(data[68:100])
+	if !targetBlock.IsUint64() {
+		return
+	}
+	w.observations.RecordPending(slot, targetBlock.Uint64(), sender, tip)
+}
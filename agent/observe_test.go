@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"go-bundles-go/chain"
+	"go-bundles-go/contracts/mevsim"
+	"math/big"
+	"testing"
+)
+
+func TestMempoolWatcherRecordsRivalBids(t *testing.T) {
+	mevsimAbi, err := mevsim.MevSimMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chainID := big.NewInt(1337)
+	mevsimAddr := mevsim.Address()
+	observations := NewObservations()
+	w := &MempoolWatcher{
+		signer:       types.LatestSignerForChainID(chainID),
+		mevsimAddr:   mevsimAddr,
+		observations: observations,
+		auctionIDs:   [][]byte{mevsimAbi.Methods["auction"].ID, mevsimAbi.Methods["auctionWithBurn"].ID},
+	}
+
+	var txs []*types.Transaction
+	baseFee := big.NewInt(10e9)
+	for i, tip := range []int64{2e9, 7e9, 3e9} {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := mevsimAbi.Pack("auction", big.NewInt(1), big.NewInt(int64(i)), big.NewInt(100))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx, err := types.SignTx(chain.NewBidTx(types.DynamicFeeTxType, chainID, 0, mevsimAddr, 100000, baseFee, big.NewInt(tip), big.NewInt(0), data, nil), w.signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.record(tx, baseFee)
+		txs = append(txs, tx)
+	}
+	// other slot doesn't count
+	data, err := mevsimAbi.Pack("auctionWithBurn", big.NewInt(2), big.NewInt(0), big.NewInt(100), big.NewInt(0), []byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := types.SignTx(chain.NewBidTx(types.LegacyTxType, chainID, 0, mevsimAddr, 100000, baseFee, big.NewInt(50e9), big.NewInt(0), data, nil), w.signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.record(tx, baseFee)
+
+	best := observations.PendingBest(big.NewInt(1), 100, crypto.PubkeyToAddress(key.PublicKey))
+	if best == nil || best.Int64() != 7e9 {
+		t.Fatalf("best pending tip %v, expected 7 gwei", best)
+	}
+	// the best bidder sees the second best rival
+	bestSender, err := types.Sender(w.signer, txs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if best := observations.PendingBest(big.NewInt(1), 100, bestSender); best == nil || best.Int64() != 3e9 {
+		t.Errorf("best rival tip %v, expected 3 gwei", best)
+	}
+	if best := observations.PendingBest(big.NewInt(2), 100, bestSender); best == nil || best.Int64() != 50e9 {
+		t.Errorf("best tip of slot 2 %v, expected 50 gwei", best)
+	}
+	observations.prunePending(100)
+	if best := observations.PendingBest(big.NewInt(1), 100, bestSender); best != nil {
+		t.Errorf("pruned bids still reported, best %s", best.String())
+	}
+}
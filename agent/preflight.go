@@ -0,0 +1,276 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"go-bundles-go/chain"
+	"go-bundles-go/contracts/mevsim"
+	"go-bundles-go/ratelimit"
+	"go-bundles-go/valuation"
+	"golang.org/x/time/rate"
+	"math"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type PreflightCheck struct {
+	Name    string
+	Ok      bool
+	Details string
+}
+
+// RunPreflight checks that MevSim is deployed at mevsimAddr and usable, agents can afford bids they plan to send
+// and relay is reachable with relayHTTP, the client agents send bundles with, unless relayUrl is empty.
+func RunPreflight(ctx context.Context, client *ethclient.Client, chainID *big.Int, mevsimAddr common.Address, agents []*BundleAgent, relayUrl string, relayHTTP *http.Client) []PreflightCheck {
+	checks := []PreflightCheck{{Name: "chain id", Ok: true, Details: chainID.String()}}
+
+	codeCheck := checkMevSimCode(ctx, client, mevsimAddr)
+	checks = append(checks, codeCheck)
+	if codeCheck.Ok {
+		checks = append(checks, checkGetSlot(ctx, client, mevsimAddr, agents))
+		checks = append(checks, checkBalances(ctx, client, mevsimAddr, agents))
+	}
+	if relayUrl != "" {
+		checks = append(checks, checkRelay(ctx, relayUrl, relayHTTP))
+	}
+	return checks
+}
+
+// PrintPreflight prints go/no-go table and returns true if all checks passed
+func PrintPreflight(checks []PreflightCheck) bool {
+	allOk := true
+	fmt.Printf("%-10s %-6s %s\n", "Check", "Status", "Details")
+	for _, check := range checks {
+		status := "GO"
+		if !check.Ok {
+			status = "NO-GO"
+			allOk = false
+		}
+		fmt.Printf("%-10s %-6s %s\n", check.Name, status, check.Details)
+	}
+	return allOk
+}
+
+func checkMevSimCode(ctx context.Context, client *ethclient.Client, mevsimAddr common.Address) PreflightCheck {
+	check := PreflightCheck{Name: "code"}
+	code, err := client.CodeAt(ctx, mevsimAddr, nil)
+	if err != nil {
+		check.Details = err.Error()
+		return check
+	}
+	if len(code) == 0 {
+		check.Details = fmt.Sprintf("no code at %s, run deploy or fix -mevsim-addr", mevsimAddr.Hex())
+		return check
+	}
+	expectedCode, _, _, err := runtime.Create(mevsim.Bytecode, nil)
+	if err != nil {
+		check.Details = fmt.Sprintf("failed to compute expected code: %v", err)
+		return check
+	}
+	if !bytes.Equal(code, expectedCode) {
+		check.Details = fmt.Sprintf("code hash at %s is %s, expected %s", mevsimAddr.Hex(), crypto.Keccak256Hash(code).Hex(), crypto.Keccak256Hash(expectedCode).Hex())
+		return check
+	}
+	check.Ok = true
+	check.Details = fmt.Sprintf("MevSim at %s, code hash %s", mevsimAddr.Hex(), crypto.Keccak256Hash(code).Hex())
+	return check
+}
+
+func checkGetSlot(ctx context.Context, client *ethclient.Client, mevsimAddr common.Address, agents []*BundleAgent) PreflightCheck {
+	check := PreflightCheck{Name: "getSlot"}
+	caller, err := mevsim.NewMevSimCaller(mevsimAddr, client)
+	if err != nil {
+		check.Details = err.Error()
+		return check
+	}
+	var values []string
+	seen := make(map[string]bool)
+	for _, agent := range agents {
+		if seen[agent.Slot.String()] {
+			continue
+		}
+		seen[agent.Slot.String()] = true
+		value, err := caller.GetSlot(nil, agent.Slot)
+		if err != nil {
+			check.Details = fmt.Sprintf("slot %s: %v", agent.Slot.String(), err)
+			return check
+		}
+		values = append(values, fmt.Sprintf("%s=%s", agent.Slot.String(), value.String()))
+	}
+	check.Ok = true
+	check.Details = strings.Join(values, " ")
+	return check
+}
+
+// checkBalances verifies that every agent can pay max fee of the last bid it sends for a block.
+// Budget of agents with strategies bounded neither by themselves nor by valuation is estimated from their first bid.
+func checkBalances(ctx context.Context, client *ethclient.Client, mevsimAddr common.Address, agents []*BundleAgent) PreflightCheck {
+	check := PreflightCheck{Name: "balances"}
+	header, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		check.Details = err.Error()
+		return check
+	}
+	caller, err := mevsim.NewMevSimCaller(mevsimAddr, client)
+	if err != nil {
+		check.Details = err.Error()
+		return check
+	}
+	mevsimAbi, err := mevsim.MevSimMetaData.GetAbi()
+	if err != nil {
+		check.Details = err.Error()
+		return check
+	}
+	baseFee := header.BaseFee
+	if baseFee == nil {
+		baseFee = big.NewInt(0)
+	}
+
+	var (
+		poor      []string
+		estimated int
+		maxBudget = big.NewInt(0)
+	)
+	for _, agent := range agents {
+		gasLimit := agent.GasLimit
+		if gasLimit == 0 {
+			slotValue, err := caller.GetSlot(nil, agent.Slot)
+			if err != nil {
+				check.Details = err.Error()
+				return check
+			}
+			data, err := agent.auctionCallData(mevsimAbi, slotValue, header.Number.Uint64()+1)
+			if err != nil {
+				check.Details = err.Error()
+				return check
+			}
+			estimatedGas, err := client.EstimateGas(ctx, ethereum.CallMsg{From: agent.Signer.Address(), To: &mevsimAddr, Data: data})
+			if err != nil {
+				check.Details = fmt.Sprintf("gas estimation for slot %s failed: %v", agent.Slot.String(), err)
+				return check
+			}
+			gasLimit = chain.ApplyGasMultiplier(estimatedGas, agent.GasMultiplier)
+		}
+
+		// effective gas price of the last bid agent sends during one block
+		bidRate, rateKnown := agent.preflightBidRate(agents)
+		windowStart, windowEnd := agent.Window.Bounds(agent.slotDuration())
+		bidsPerBlock := int64(bidRate * (windowEnd - windowStart).Seconds())
+		if bidsPerBlock < 1 {
+			bidsPerBlock = 1
+		}
+		budget, exact := agent.bidBudget(baseFee, gasLimit, int(bidsPerBlock))
+		if !exact || !rateKnown {
+			estimated++
+		}
+		if budget.Cmp(maxBudget) > 0 {
+			maxBudget = budget
+		}
+
+		balance, err := client.BalanceAt(ctx, agent.Signer.Address(), nil)
+		if err != nil {
+			check.Details = err.Error()
+			return check
+		}
+		if balance.Cmp(budget) < 0 {
+			poor = append(poor, fmt.Sprintf("%s(%s<%s)", agent.Signer.Address().Hex(), chain.WeiToUnit(balance, 1e18).String(), chain.WeiToUnit(budget, 1e18).String()))
+		}
+	}
+	if len(poor) > 0 {
+		if len(poor) > 3 {
+			poor = append(poor[:3], "...")
+		}
+		check.Details = fmt.Sprintf("%d/%d agents can't afford bid budget(eth): %s", len(poor), len(agents), strings.Join(poor, " "))
+	} else {
+		check.Ok = true
+		check.Details = fmt.Sprintf("%d agents funded, max bid budget %s eth", len(agents), chain.WeiToUnit(maxBudget, 1e18).String())
+	}
+	if estimated > 0 {
+		check.Details += fmt.Sprintf(", budget of %d agents is estimated, their bid rate is unknown or strategy bids above the first bid", estimated)
+	}
+	return check
+}
+
+// preflightBidRate returns bids per second agent sends and whether the rate is known.
+// Agents without their own rate get fair share of Scheduler among agents sharing it, agents in priority mode can get more.
+func (b *BundleAgent) preflightBidRate(agents []*BundleAgent) (float64, bool) {
+	if b.Scheduler == nil || b.Scheduler.Limit() == rate.Inf {
+		return float64(b.BidRate), b.BidRate > 0
+	}
+	limit := float64(b.Scheduler.Limit())
+	if b.BidRate > 0 {
+		return math.Min(float64(b.BidRate), limit), true
+	}
+	shared := 0
+	for _, agent := range agents {
+		if agent.Scheduler == b.Scheduler {
+			shared++
+		}
+	}
+	return limit / float64(shared), b.Scheduler.Mode() == ratelimit.ModeFair
+}
+
+// bidBudget returns the most agent pays for a block: max fee of the highest bid of its strategy among bids,
+// capped by the highest valuation. It's false if neither bounds bids and the budget is of the first bid only.
+func (b *BundleAgent) bidBudget(baseFee *big.Int, gasLimit uint64, bids int) (*big.Int, bool) {
+	var (
+		effGasPrice *big.Int
+		exact       bool
+	)
+	strategy := b.bidStrategy()
+	if maxBidder, ok := strategy.(MaxBidder); ok {
+		effGasPrice = maxBidder.MaxBid(bids)
+		exact = true
+	} else {
+		effGasPrice = strategy.Bid(SlotState{})
+	}
+	budget := new(big.Int).Set(baseFee)
+	if effGasPrice != nil {
+		budget.Add(budget, effGasPrice)
+	}
+	budget.Mul(budget, new(big.Int).SetUint64(gasLimit))
+	// capped bids don't pay more than valuation
+	if bounded, ok := b.Valuation.(valuation.Bounded); ok {
+		if max := bounded.Max(); !exact || max.Cmp(budget) < 0 {
+			budget = max
+		}
+		exact = true
+	}
+	return budget, exact
+}
+
+// checkRelay checks that relay answers http requests, any response below 500 is accepted
+func checkRelay(ctx context.Context, relayUrl string, relayHTTP *http.Client) PreflightCheck {
+	check := PreflightCheck{Name: "relay"}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	body := strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, relayUrl, body)
+	if err != nil {
+		check.Details = err.Error()
+		return check
+	}
+	req.Header.Set("Content-Type", "application/json")
+	start := time.Now()
+	resp, err := relayHTTP.Do(req)
+	if err != nil {
+		check.Details = err.Error()
+		return check
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		check.Details = fmt.Sprintf("%s responded %s", relayUrl, resp.Status)
+		return check
+	}
+	check.Ok = true
+	check.Details = fmt.Sprintf("%s responded %s in %s", relayUrl, resp.Status, time.Since(start).Round(time.Millisecond))
+	return check
+}
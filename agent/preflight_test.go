@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"context"
+	"go-bundles-go/ratelimit"
+	"go-bundles-go/transport"
+	"go-bundles-go/valuation"
+	"golang.org/x/time/rate"
+	"math/big"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBidBudget(t *testing.T) {
+	const gasLimit = 100000
+	baseFee := big.NewInt(10e9)
+	bandit, err := NewBanditStrategy([]BanditArm{
+		{Start: big.NewInt(1e9), Increment: big.NewInt(1e9)},
+		{Start: big.NewInt(5e9), Increment: big.NewInt(0)},
+	}, big.NewInt(10e9), 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixed, err := valuation.New(valuation.ModelFixed, 1e15, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uniform, err := valuation.New(valuation.ModelUniform, 2e15, 1e15, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	normal, err := valuation.New(valuation.ModelNormal, 1e15, 1e14, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	margin := MarginStrategy{Start: big.NewInt(2e9), Margin: big.NewInt(1e9)}
+
+	tests := []struct {
+		name      string
+		strategy  Strategy
+		valuation valuation.Distribution
+		// effective gas price the budget pays or valuation cap in wei
+		effGasPrice int64
+		cap         int64
+		exact       bool
+	}{
+		{name: "linear", effGasPrice: 1e9 + 9*1e8, exact: true},
+		{name: "bandit", strategy: bandit, effGasPrice: 1e9 + 9*1e9, exact: true},
+		{name: "margin", strategy: margin, effGasPrice: 2e9, exact: false},
+		{name: "linear capped by valuation", valuation: fixed, cap: 1e15, exact: true},
+		{name: "linear below valuation", valuation: uniform, effGasPrice: 1e9 + 9*1e8, exact: true},
+		{name: "margin bounded by valuation", strategy: margin, valuation: uniform, cap: 3e15, exact: true},
+		{name: "linear with unbounded valuation", valuation: normal, effGasPrice: 1e9 + 9*1e8, exact: true},
+	}
+	for _, test := range tests {
+		agent := New(Config{
+			StartingEffGasPrice:  big.NewInt(1e9),
+			IncrementEffGasPrice: big.NewInt(1e8),
+			Strategy:             test.strategy,
+			Valuation:            test.valuation,
+		})
+		budget, exact := agent.bidBudget(baseFee, gasLimit, 10)
+		expected := big.NewInt(test.cap)
+		if test.cap == 0 {
+			expected = new(big.Int).Add(baseFee, big.NewInt(test.effGasPrice))
+			expected.Mul(expected, big.NewInt(gasLimit))
+		}
+		if budget.Cmp(expected) != 0 || exact != test.exact {
+			t.Errorf("%s: budget %s exact %v, expected %s exact %v", test.name, budget, exact, expected, test.exact)
+		}
+	}
+}
+
+func TestPreflightBidRate(t *testing.T) {
+	fair := ratelimit.NewScheduler(ratelimit.ModeFair, []int{0}, rate.NewLimiter(40, 1))
+	priority := ratelimit.NewScheduler(ratelimit.ModePriority, []int{0}, rate.NewLimiter(40, 1))
+	unlimited := ratelimit.NewScheduler(ratelimit.ModeFair, []int{0})
+
+	tests := []struct {
+		name      string
+		bidRate   uint64
+		scheduler *ratelimit.Scheduler
+		agents    int
+		rate      float64
+		known     bool
+	}{
+		{name: "own rate", bidRate: 10, agents: 1, rate: 10, known: true},
+		{name: "own rate below scheduler", bidRate: 10, scheduler: fair, agents: 2, rate: 10, known: true},
+		{name: "own rate above scheduler", bidRate: 100, scheduler: fair, agents: 2, rate: 40, known: true},
+		{name: "fair share", scheduler: fair, agents: 4, rate: 10, known: true},
+		{name: "priority share", scheduler: priority, agents: 4, rate: 10, known: false},
+		{name: "unlimited scheduler", scheduler: unlimited, agents: 2, rate: 0, known: false},
+	}
+	for _, test := range tests {
+		agents := make([]*BundleAgent, test.agents)
+		for i := range agents {
+			agents[i] = New(Config{BidRate: test.bidRate, Scheduler: test.scheduler})
+		}
+		bidRate, known := agents[0].preflightBidRate(agents)
+		if bidRate != test.rate || known != test.known {
+			t.Errorf("%s: rate %f known %v, expected %f known %v", test.name, bidRate, known, test.rate, test.known)
+		}
+	}
+}
+
+func TestCheckRelayUsesClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer server.Close()
+
+	if check := checkRelay(context.Background(), server.URL, http.DefaultClient); check.Ok {
+		t.Errorf("relay check passed without authentication: %s", check.Details)
+	}
+	authenticated := transport.NewClient(transport.NewTransport(transport.Options{}), time.Second, transport.Endpoint{BearerToken: "token"})
+	if check := checkRelay(context.Background(), server.URL, authenticated); !check.Ok {
+		t.Errorf("relay check failed with authenticated client: %s", check.Details)
+	}
+}
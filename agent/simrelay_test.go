@@ -0,0 +1,323 @@
+package agent
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/metachris/flashbotsrpc"
+	"go-bundles-go/chain"
+	"go-bundles-go/contracts/mevsim"
+	"go-bundles-go/internal/simchain"
+	"go-bundles-go/wallet"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testMnemonic = "panic keen way shuffle post attract clever country juice point pulp february"
+
+// simRelay is in-process stand-in for the relay, it keeps bundles sent by agents
+// and builds blocks on simulated backend from the best bid of every slot
+type simRelay struct {
+	backend *simchain.Backend
+	signer  types.Signer
+
+	mu sync.Mutex
+	// bundle txs by target block
+	bundles map[uint64][]*types.Transaction
+}
+
+func newSimRelay(backend *simchain.Backend) *simRelay {
+	return &simRelay{
+		backend: backend,
+		signer:  types.LatestSignerForChainID(backend.Blockchain().Config().ChainID),
+		bundles: make(map[uint64][]*types.Transaction),
+	}
+}
+
+func decodeBundleTxs(txs []string) ([]*types.Transaction, error) {
+	var result []*types.Transaction
+	for _, txHex := range txs {
+		txBytes, err := hexutil.Decode(txHex)
+		if err != nil {
+			return nil, err
+		}
+		tx := new(types.Transaction)
+		err = tx.UnmarshalBinary(txBytes)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, tx)
+	}
+	return result, nil
+}
+
+func (r *simRelay) FlashbotsSendBundle(privKey *ecdsa.PrivateKey, param flashbotsrpc.FlashbotsSendBundleRequest) (flashbotsrpc.FlashbotsSendBundleResponse, error) {
+	targetBlock, err := hexutil.DecodeUint64(param.BlockNumber)
+	if err != nil {
+		return flashbotsrpc.FlashbotsSendBundleResponse{}, err
+	}
+	txs, err := decodeBundleTxs(param.Txs)
+	if err != nil {
+		return flashbotsrpc.FlashbotsSendBundleResponse{}, err
+	}
+	if len(txs) != 1 {
+		return flashbotsrpc.FlashbotsSendBundleResponse{}, fmt.Errorf("sim relay accepts single tx bundles, got %d txs", len(txs))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bundles[targetBlock] = append(r.bundles[targetBlock], txs[0])
+	return flashbotsrpc.FlashbotsSendBundleResponse{BundleHash: txs[0].Hash().Hex()}, nil
+}
+
+// FlashbotsCallBundle reports estimated gas of bundle txs on pending state as gas used
+func (r *simRelay) FlashbotsCallBundle(privKey *ecdsa.PrivateKey, param flashbotsrpc.FlashbotsCallBundleParam) (flashbotsrpc.FlashbotsCallBundleResponse, error) {
+	txs, err := decodeBundleTxs(param.Txs)
+	if err != nil {
+		return flashbotsrpc.FlashbotsCallBundleResponse{}, err
+	}
+	var res flashbotsrpc.FlashbotsCallBundleResponse
+	for _, tx := range txs {
+		from, err := types.Sender(r.signer, tx)
+		if err != nil {
+			return flashbotsrpc.FlashbotsCallBundleResponse{}, err
+		}
+		result := flashbotsrpc.FlashbotsCallBundleResult{FromAddress: from.Hex(), TxHash: tx.Hash().Hex()}
+		gas, err := r.backend.EstimateGas(context.Background(), ethereum.CallMsg{
+			From:       from,
+			To:         tx.To(),
+			GasFeeCap:  tx.GasFeeCap(),
+			GasTipCap:  tx.GasTipCap(),
+			Value:      tx.Value(),
+			Data:       tx.Data(),
+			AccessList: tx.AccessList(),
+		})
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.GasUsed = int64(gas)
+		}
+		res.Results = append(res.Results, result)
+		res.TotalGasUsed += result.GasUsed
+	}
+	return res, nil
+}
+
+// bidders returns number of distinct senders that bid for targetBlock
+func (r *simRelay) bidders(targetBlock uint64) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	senders := make(map[common.Address]bool)
+	for _, tx := range r.bundles[targetBlock] {
+		from, err := types.Sender(r.signer, tx)
+		if err == nil {
+			senders[from] = true
+		}
+	}
+	return len(senders)
+}
+
+// BuildBlock includes the highest tip bid of every slot into the next block and commits it
+func (r *simRelay) BuildBlock(ctx context.Context) error {
+	header, err := r.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return err
+	}
+	targetBlock := header.Number.Uint64() + 1
+	baseFee, err := r.backend.SuggestGasPrice(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	txs := r.bundles[targetBlock]
+	for block := range r.bundles {
+		if block <= targetBlock {
+			delete(r.bundles, block)
+		}
+	}
+	r.mu.Unlock()
+
+	// auction calldata starts with the slot argument
+	best := make(map[common.Hash]*types.Transaction)
+	for _, tx := range txs {
+		if len(tx.Data()) < 4+32 {
+			continue
+		}
+		slot := common.BytesToHash(tx.Data()[4 : 4+32])
+		tip, err := tx.EffectiveGasTip(baseFee)
+		if err != nil {
+			continue
+		}
+		if current, ok := best[slot]; ok {
+			currentTip, _ := current.EffectiveGasTip(baseFee)
+			if currentTip.Cmp(tip) >= 0 {
+				continue
+			}
+		}
+		best[slot] = tx
+	}
+	for _, tx := range best {
+		err = r.backend.SendTransaction(ctx, tx)
+		if err != nil {
+			return err
+		}
+	}
+	r.backend.Commit()
+	return nil
+}
+
+// simHarness is simulated chain with deployed MevSim and funded searcher wallets
+type simHarness struct {
+	t          *testing.T
+	backend    *simchain.Backend
+	relay      *simRelay
+	chainID    *big.Int
+	searchers  []*ecdsa.PrivateKey
+	mevsimAddr common.Address
+	mevsim     *mevsim.MevSim
+}
+
+func newSimHarness(t *testing.T, searchers int) *simHarness {
+	t.Helper()
+	indices := make([]int, searchers)
+	for i := range indices {
+		indices[i] = i + 1
+	}
+	master, searcherKeys, err := wallet.Derive(testMnemonic, wallet.DefaultPath, 0, indices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balance := new(big.Int).Mul(big.NewInt(1000), big.NewInt(1e18))
+	alloc := core.GenesisAlloc{crypto.PubkeyToAddress(master.PublicKey): {Balance: balance}}
+	for _, key := range searcherKeys {
+		alloc[crypto.PubkeyToAddress(key.PublicKey)] = core.GenesisAccount{Balance: balance}
+	}
+	backend := simchain.New(t, alloc)
+	chainID := backend.Blockchain().Config().ChainID
+
+	gasPrice, err := backend.SuggestGasPrice(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployTx, err := types.SignTx(types.NewContractCreation(0, big.NewInt(0), 3000000, gasPrice, mevsim.Bytecode), types.LatestSignerForChainID(chainID), master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = backend.SendTransaction(context.Background(), deployTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Commit()
+	receipt, err := backend.TransactionReceipt(context.Background(), deployTx.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatal("MevSim deployment failed")
+	}
+	mevsimContract, err := mevsim.NewMevSim(receipt.ContractAddress, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &simHarness{
+		t:          t,
+		backend:    backend,
+		relay:      newSimRelay(backend),
+		chainID:    chainID,
+		searchers:  searcherKeys,
+		mevsimAddr: receipt.ContractAddress,
+		mevsim:     mevsimContract,
+	}
+}
+
+// newAgent returns agent of searcher i that bids for slot with dynamic fee txs and estimated gas
+func (h *simHarness) newAgent(i int, slot int64, startingEffGasPrice int64) *BundleAgent {
+	return New(Config{
+		Slot:                 big.NewInt(slot),
+		StartingEffGasPrice:  big.NewInt(startingEffGasPrice),
+		IncrementEffGasPrice: big.NewInt(1),
+		BidRate:              200,
+		TxType:               types.DynamicFeeTxType,
+		AccessListMode:       AccessListNone,
+		GasEstimateMode:      GasEstimateRPC,
+		GasMultiplier:        1.2,
+		ChainID:              h.chainID,
+		Signer:               wallet.NewKeySigner(h.searchers[i]),
+		RelayKey:             h.searchers[i],
+	})
+}
+
+// runAgents runs agents until the test ends
+func (h *simHarness) runAgents(agents ...*BundleAgent) {
+	h.runAgentsWith(h.backend, agents...)
+}
+
+// runAgentsWith runs agents with node backend until the test ends
+func (h *simHarness) runAgentsWith(backend chain.Backend, agents ...*BundleAgent) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	h.t.Cleanup(func() {
+		cancel()
+		wg.Wait()
+	})
+	for _, agent := range agents {
+		agent := agent
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = agent.Run(ctx, backend, h.relay, h.mevsimAddr)
+		}()
+	}
+}
+
+// buildBlock waits until bidders agents bid for the next block and builds it
+func (h *simHarness) buildBlock(bidders int) uint64 {
+	h.t.Helper()
+	header, err := h.backend.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	targetBlock := header.Number.Uint64() + 1
+
+	deadline := time.Now().Add(10 * time.Second)
+	for h.relay.bidders(targetBlock) < bidders {
+		if time.Now().After(deadline) {
+			h.t.Fatalf("timeout waiting for %d bidders for block %d, got %d", bidders, targetBlock, h.relay.bidders(targetBlock))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	err = h.relay.BuildBlock(context.Background())
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return targetBlock
+}
+
+func (h *simHarness) slotValue(slot int64) uint64 {
+	h.t.Helper()
+	value, err := h.mevsim.GetSlot(nil, big.NewInt(slot))
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return value.Uint64()
+}
+
+func (h *simHarness) lastWinner(slot int64) (common.Address, uint64) {
+	h.t.Helper()
+	winner, err := h.mevsim.GetLastWinner(nil, big.NewInt(slot))
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return winner.Bidder, winner.BlockNumber.Uint64()
+}
@@ -0,0 +1,229 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"go-bundles-go/chain"
+	"go-bundles-go/contracts/mevsim"
+	"math/big"
+	"sync/atomic"
+	"time"
+)
+
+// maxStateBatch is max number of calls in one json-rpc batch, nodes limit batch size
+const maxStateBatch = 1000
+
+// BlockState is chain state agents read on every new block
+type BlockState struct {
+	Header     *types.Header
+	SlotValues map[string]*big.Int
+	Nonces     map[common.Address]uint64
+	// suggested gas price minus suggested tip, the base fee agents bid over
+	BaseFee *big.Int
+}
+
+// StateSource publishes state of the latest block to agents, Latest returns nil until the first state is read
+type StateSource interface {
+	Latest() *BlockState
+}
+
+// StateBackend is node api used by StateCoordinator, implemented by *chain.NodeBackend
+type StateBackend interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	BatchCallContext(ctx context.Context, b []gethrpc.BatchElem) error
+}
+
+// StateCoordinator reads slot values, nonces and fee data of all agents in one json-rpc batch per block
+// instead of every agent making its own calls
+type StateCoordinator struct {
+	client     StateBackend
+	mevsimAddr common.Address
+	mevsimAbi  *abi.ABI
+	slots      []*big.Int
+	accounts   []common.Address
+	interval   time.Duration
+
+	latest  atomic.Pointer[BlockState]
+	batches atomic.Uint64
+}
+
+// NewStateCoordinator creates coordinator of slots and accounts that polls head every interval
+func NewStateCoordinator(client StateBackend, mevsimAddr common.Address, slots []*big.Int, accounts []common.Address, interval time.Duration) (*StateCoordinator, error) {
+	mevsimAbi, err := mevsim.MevSimMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	// agents of a slot share its value, it's read once
+	var uniqueSlots []*big.Int
+	seen := make(map[string]bool)
+	for _, slot := range slots {
+		if !seen[slot.String()] {
+			seen[slot.String()] = true
+			uniqueSlots = append(uniqueSlots, slot)
+		}
+	}
+	return &StateCoordinator{
+		client:     client,
+		mevsimAddr: mevsimAddr,
+		mevsimAbi:  mevsimAbi,
+		slots:      uniqueSlots,
+		accounts:   accounts,
+		interval:   interval,
+	}, nil
+}
+
+func (c *StateCoordinator) Latest() *BlockState {
+	return c.latest.Load()
+}
+
+// Batches returns number of json-rpc batches sent
+func (c *StateCoordinator) Batches() uint64 {
+	return c.batches.Load()
+}
+
+// Run polls head and publishes state of every new block until ctx is canceled
+func (c *StateCoordinator) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	var failures int
+	for {
+		err := c.update(ctx)
+		if err != nil {
+			if failures == 0 {
+				fmt.Println("error reading block state", err)
+			}
+			failures++
+		} else if failures > 0 {
+			fmt.Println("block state recovered", "failures", failures)
+			failures = 0
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *StateCoordinator) update(ctx context.Context) error {
+	header, err := c.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// a block replacing the head at the same or lower height is read again, its state differs
+	if latest := c.latest.Load(); latest != nil && latest.Header.Hash() == header.Hash() {
+		return nil
+	}
+	start := time.Now()
+	state, err := c.read(ctx, header)
+	if err != nil {
+		return err
+	}
+	c.latest.Store(state)
+	fmt.Println("block state", "block", header.Number, "slots", len(c.slots), "accounts", len(c.accounts),
+		"calls", len(c.slots)+len(c.accounts)+2, "duration", time.Since(start).Round(time.Microsecond))
+	return nil
+}
+
+// read gets state of block in batches of json-rpc calls
+func (c *StateCoordinator) read(ctx context.Context, header *types.Header) (*BlockState, error) {
+	var (
+		elems      []gethrpc.BatchElem
+		slotValues = make([]hexutil.Bytes, len(c.slots))
+		nonces     = make([]hexutil.Uint64, len(c.accounts))
+		gasPrice   hexutil.Big
+		gasTipCap  hexutil.Big
+	)
+	// state of the header's block by hash (EIP-1898), a number could resolve to another block of a reorg
+	block := map[string]interface{}{"blockHash": header.Hash(), "requireCanonical": true}
+	for i, slot := range c.slots {
+		data, err := c.mevsimAbi.Pack("getSlot", slot)
+		if err != nil {
+			return nil, err
+		}
+		call := map[string]interface{}{"to": c.mevsimAddr, "data": hexutil.Bytes(data)}
+		elems = append(elems, gethrpc.BatchElem{Method: "eth_call", Args: []interface{}{call, block}, Result: &slotValues[i]})
+	}
+	for i, account := range c.accounts {
+		elems = append(elems, gethrpc.BatchElem{Method: "eth_getTransactionCount", Args: []interface{}{account, "pending"}, Result: &nonces[i]})
+	}
+	elems = append(elems,
+		gethrpc.BatchElem{Method: "eth_gasPrice", Result: &gasPrice},
+		gethrpc.BatchElem{Method: "eth_maxPriorityFeePerGas", Result: &gasTipCap},
+	)
+
+	for from := 0; from < len(elems); from += maxStateBatch {
+		to := from + maxStateBatch
+		if to > len(elems) {
+			to = len(elems)
+		}
+		err := c.client.BatchCallContext(ctx, elems[from:to])
+		c.batches.Add(1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, elem := range elems {
+		if elem.Error != nil {
+			return nil, fmt.Errorf("%s: %w", elem.Method, elem.Error)
+		}
+	}
+
+	state := &BlockState{
+		Header:     header,
+		SlotValues: make(map[string]*big.Int),
+		Nonces:     make(map[common.Address]uint64),
+		BaseFee:    new(big.Int).Sub(gasPrice.ToInt(), gasTipCap.ToInt()),
+	}
+	for i, slot := range c.slots {
+		values, err := c.mevsimAbi.Unpack("getSlot", slotValues[i])
+		if err != nil {
+			return nil, fmt.Errorf("getSlot(%s): %w", slot, err)
+		}
+		state.SlotValues[slot.String()] = values[0].(*big.Int)
+	}
+	for i, account := range c.accounts {
+		state.Nonces[account] = uint64(nonces[i])
+	}
+	return state, nil
+}
+
+// blockState returns slot value, pending nonce and base fee of the agent from state,
+// or reads them with separate calls if state is nil
+func (b *BundleAgent) blockState(ctx context.Context, client chain.Backend, mevsimSession *mevsim.MevSimSession, state *BlockState) (*big.Int, uint64, *big.Int, error) {
+	address := b.Signer.Address()
+	if state != nil {
+		slotValue, ok := state.SlotValues[b.Slot.String()]
+		if !ok {
+			return nil, 0, nil, fmt.Errorf("block state has no value of slot %s", b.Slot)
+		}
+		nonce, ok := state.Nonces[address]
+		if !ok {
+			return nil, 0, nil, fmt.Errorf("block state has no nonce of %s", address.Hex())
+		}
+		return slotValue, nonce, state.BaseFee, nil
+	}
+
+	slotValue, err := mevsimSession.GetSlot(b.Slot)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("getting slot value: %w", err)
+	}
+	nonce, err := client.PendingNonceAt(ctx, address)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("getting nonce: %w", err)
+	}
+	suggestedGasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("getting gas price: %w", err)
+	}
+	suggestedTip, err := client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("getting gas tip: %w", err)
+	}
+	return slotValue, nonce, new(big.Int).Sub(suggestedGasPrice, suggestedTip), nil
+}
@@ -0,0 +1,145 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"go-bundles-go/internal/simchain"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// simBatchBackend serves json-rpc batches of StateCoordinator from simulated backend
+type simBatchBackend struct {
+	*simchain.Backend
+
+	mu sync.Mutex
+	// sizes of received batches
+	batches []int
+}
+
+func (b *simBatchBackend) BatchCallContext(ctx context.Context, elems []gethrpc.BatchElem) error {
+	b.mu.Lock()
+	b.batches = append(b.batches, len(elems))
+	b.mu.Unlock()
+	for i := range elems {
+		elem := &elems[i]
+		switch elem.Method {
+		case "eth_call":
+			call := elem.Args[0].(map[string]interface{})
+			to := call["to"].(common.Address)
+			blockHash := elem.Args[1].(map[string]interface{})["blockHash"].(common.Hash)
+			header, err := b.HeaderByHash(ctx, blockHash)
+			if err != nil {
+				return err
+			}
+			out, err := b.CallContract(ctx, ethereum.CallMsg{To: &to, Data: call["data"].(hexutil.Bytes)}, header.Number)
+			elem.Error = err
+			*elem.Result.(*hexutil.Bytes) = out
+		case "eth_getTransactionCount":
+			nonce, err := b.PendingNonceAt(ctx, elem.Args[0].(common.Address))
+			elem.Error = err
+			*elem.Result.(*hexutil.Uint64) = hexutil.Uint64(nonce)
+		case "eth_gasPrice":
+			gasPrice, err := b.SuggestGasPrice(ctx)
+			elem.Error = err
+			if err == nil {
+				*elem.Result.(*hexutil.Big) = hexutil.Big(*gasPrice)
+			}
+		case "eth_maxPriorityFeePerGas":
+			tip, err := b.SuggestGasTipCap(ctx)
+			elem.Error = err
+			if err == nil {
+				*elem.Result.(*hexutil.Big) = hexutil.Big(*tip)
+			}
+		default:
+			elem.Error = fmt.Errorf("unsupported method %s", elem.Method)
+		}
+	}
+	return nil
+}
+
+// countingBackend counts per agent state reads
+type countingBackend struct {
+	*simchain.Backend
+	nonceCalls atomic.Uint64
+}
+
+func (b *countingBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	b.nonceCalls.Add(1)
+	return b.Backend.PendingNonceAt(ctx, account)
+}
+
+func TestStateCoordinatorBatchesBlockState(t *testing.T) {
+	h := newSimHarness(t, 3)
+	low := h.newAgent(0, 1, 1e9)
+	high := h.newAgent(1, 1, 50e9)
+	alone := h.newAgent(2, 2, 1e9)
+	agents := []*BundleAgent{low, high, alone}
+
+	batchBackend := &simBatchBackend{Backend: h.backend}
+	var slots []*big.Int
+	var accounts []common.Address
+	for _, a := range agents {
+		slots = append(slots, a.Slot)
+		accounts = append(accounts, a.Address())
+	}
+	coordinator, err := NewStateCoordinator(batchBackend, h.mevsimAddr, slots, accounts, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	go func() {
+		_ = coordinator.Run(ctx)
+		close(done)
+	}()
+	for _, a := range agents {
+		a.State = coordinator
+	}
+	agentBackend := &countingBackend{Backend: h.backend}
+	h.runAgentsWith(agentBackend, agents...)
+
+	const blocks = 3
+	for i := 0; i < blocks; i++ {
+		h.buildBlock(3)
+	}
+	if value := h.slotValue(1); value != blocks {
+		t.Errorf("slot 1 value %d, expected %d", value, blocks)
+	}
+	if winner, _ := h.lastWinner(1); winner != high.Address() {
+		t.Errorf("slot 1 last winner %s, expected %s", winner.Hex(), high.Address().Hex())
+	}
+	if winner, _ := h.lastWinner(2); winner != alone.Address() {
+		t.Errorf("slot 2 last winner %s, expected %s", winner.Hex(), alone.Address().Hex())
+	}
+
+	if calls := agentBackend.nonceCalls.Load(); calls != 0 {
+		t.Errorf("agents read nonces with %d calls of their own", calls)
+	}
+	batchBackend.mu.Lock()
+	batches := append([]int(nil), batchBackend.batches...)
+	batchBackend.mu.Unlock()
+	if len(batches) < blocks {
+		t.Errorf("%d batches for %d blocks", len(batches), blocks)
+	}
+	// two slots, three nonces and fee data
+	for _, size := range batches {
+		if size != 7 {
+			t.Errorf("batch of %d calls, expected 7", size)
+		}
+	}
+	if uint64(len(batches)) != coordinator.Batches() {
+		t.Errorf("coordinator counted %d batches, backend got %d", coordinator.Batches(), len(batches))
+	}
+}
@@ -0,0 +1,145 @@
+package agent
+
+import (
+	"math/big"
+	"time"
+)
+
+// DefaultSlotDuration is time between blocks on mainnet
+const DefaultSlotDuration = 12 * time.Second
+
+// SlotState is what strategy knows about the slot it bids in
+type SlotState struct {
+	// block the bids are sent for
+	TargetBlock uint64
+	// timestamp of the parent header, start of the slot
+	ParentTime time.Time
+	Duration   time.Duration
+	// time the bid is built
+	Now time.Time
+	// bids already built in this slot
+	Bids int
+	// private value of winning the slot and the highest effective gas price that doesn't pay more than it,
+	// nil if agent has no valuation
+	Valuation      *big.Int
+	MaxEffGasPrice *big.Int
+
+	// rival bids filled from Observations, nil if nothing was observed.
+	// PrevWinningTip is tip per gas of the last win of the slot before the target block.
+	PrevWinningTip *big.Int
+	PrevWinnerOurs bool
+	// the highest tip per gas of rivals' bids for the target block seen in the mempool
+	RivalPendingTip *big.Int
+}
+
+// SlotStart returns start of the current slot. If the target block is late its slot is assumed missed
+// and the block is expected in the next slot, like a missed proposal on the beacon chain.
+func (s SlotState) SlotStart() time.Time {
+	elapsed := s.Now.Sub(s.ParentTime)
+	if elapsed < s.Duration || s.Duration <= 0 {
+		return s.ParentTime
+	}
+	return s.ParentTime.Add(elapsed / s.Duration * s.Duration)
+}
+
+// NextBlock returns expected time of the target block
+func (s SlotState) NextBlock() time.Time {
+	return s.SlotStart().Add(s.Duration)
+}
+
+// UntilNextBlock returns time left until the target block
+func (s SlotState) UntilNextBlock() time.Duration {
+	return s.NextBlock().Sub(s.Now)
+}
+
+// Elapsed returns time since the start of the current slot
+func (s SlotState) Elapsed() time.Duration {
+	return s.Now.Sub(s.SlotStart())
+}
+
+// Strategy decides effective gas price of bids
+type Strategy interface {
+	// Bid returns effective gas price of the next bid, nil skips it
+	Bid(state SlotState) *big.Int
+}
+
+// MaxBidder is implemented by strategies whose bids don't depend on rivals, so their cost can be bounded up front
+type MaxBidder interface {
+	// MaxBid returns the highest effective gas price among the first bids of a slot
+	MaxBid(bids int) *big.Int
+}
+
+// LinearStrategy starts every slot at Start and raises price by Increment with every bid
+type LinearStrategy struct {
+	Start     *big.Int
+	Increment *big.Int
+}
+
+func (l LinearStrategy) Bid(state SlotState) *big.Int {
+	price := new(big.Int).Mul(l.Increment, big.NewInt(int64(state.Bids)))
+	return price.Add(price, l.Start)
+}
+
+func (l LinearStrategy) MaxBid(bids int) *big.Int {
+	if bids < 1 {
+		bids = 1
+	}
+	return l.Bid(SlotState{Bids: bids - 1})
+}
+
+// MarginStrategy bids above the best observed rival bid: the previous winning tip of the slot
+// or rival bid for the same block seen in the mempool. Previous win of the agent itself is matched without margin,
+// Start is bid while nothing is observed.
+type MarginStrategy struct {
+	Start  *big.Int
+	Margin *big.Int
+	// margin relative to the observed bid, added to Margin
+	MarginPercent float64
+}
+
+func (m MarginStrategy) Bid(state SlotState) *big.Int {
+	var best *big.Int
+	if state.PrevWinningTip != nil {
+		if state.PrevWinnerOurs {
+			best = new(big.Int).Set(state.PrevWinningTip)
+		} else {
+			best = m.above(state.PrevWinningTip)
+		}
+	}
+	if state.RivalPendingTip != nil {
+		if above := m.above(state.RivalPendingTip); best == nil || above.Cmp(best) > 0 {
+			best = above
+		}
+	}
+	if best == nil {
+		return new(big.Int).Set(m.Start)
+	}
+	return best
+}
+
+func (m MarginStrategy) above(tip *big.Int) *big.Int {
+	// percent in basis points keeps math in integers
+	price := new(big.Int).Mul(tip, big.NewInt(int64(m.MarginPercent*100)))
+	price.Div(price, big.NewInt(10000))
+	price.Add(price, tip)
+	return price.Add(price, m.Margin)
+}
+
+// BidWindow limits bidding to part of the slot
+type BidWindow struct {
+	// bids start this long after the parent block
+	StartOffset time.Duration
+	// bids stop this long before the expected next block
+	Deadline time.Duration
+	// if > 0 bids are sent only during the last Last before the deadline
+	Last time.Duration
+}
+
+// Bounds returns offsets from the slot start where bidding starts and stops
+func (w BidWindow) Bounds(slotDuration time.Duration) (from, to time.Duration) {
+	from, to = w.StartOffset, slotDuration-w.Deadline
+	if w.Last > 0 && to-w.Last > from {
+		from = to - w.Last
+	}
+	return from, to
+}
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestSlotTiming(t *testing.T) {
+	parent := time.Unix(1700000000, 0)
+	tests := []struct {
+		now            time.Duration // since parent
+		untilNextBlock time.Duration
+		elapsed        time.Duration
+	}{
+		{now: 0, untilNextBlock: 12 * time.Second, elapsed: 0},
+		{now: 11500 * time.Millisecond, untilNextBlock: 500 * time.Millisecond, elapsed: 11500 * time.Millisecond},
+		// target block missed its slot and is expected in the next one
+		{now: 12 * time.Second, untilNextBlock: 12 * time.Second, elapsed: 0},
+		{now: 27 * time.Second, untilNextBlock: 9 * time.Second, elapsed: 3 * time.Second},
+	}
+	for _, test := range tests {
+		state := SlotState{ParentTime: parent, Duration: 12 * time.Second, Now: parent.Add(test.now)}
+		if got := state.UntilNextBlock(); got != test.untilNextBlock {
+			t.Errorf("%s after parent: until next block %s, expected %s", test.now, got, test.untilNextBlock)
+		}
+		if got := state.Elapsed(); got != test.elapsed {
+			t.Errorf("%s after parent: elapsed %s, expected %s", test.now, got, test.elapsed)
+		}
+	}
+}
+
+func TestBidWindowBounds(t *testing.T) {
+	tests := []struct {
+		window   BidWindow
+		from, to time.Duration
+	}{
+		{window: BidWindow{}, from: 0, to: 12 * time.Second},
+		{window: BidWindow{StartOffset: 2 * time.Second, Deadline: time.Second}, from: 2 * time.Second, to: 11 * time.Second},
+		{window: BidWindow{Deadline: 4 * time.Second, Last: 500 * time.Millisecond}, from: 7500 * time.Millisecond, to: 8 * time.Second},
+		// start offset is kept when it's later than the last window
+		{window: BidWindow{StartOffset: 10 * time.Second, Last: 5 * time.Second}, from: 10 * time.Second, to: 12 * time.Second},
+	}
+	for _, test := range tests {
+		from, to := test.window.Bounds(12 * time.Second)
+		if from != test.from || to != test.to {
+			t.Errorf("%+v: bounds [%s, %s), expected [%s, %s)", test.window, from, to, test.from, test.to)
+		}
+	}
+}
+
+func TestLinearStrategy(t *testing.T) {
+	strategy := LinearStrategy{Start: big.NewInt(5), Increment: big.NewInt(2)}
+	for bids, expected := range []int64{5, 7, 9} {
+		if got := strategy.Bid(SlotState{Bids: bids}); got.Int64() != expected {
+			t.Errorf("bid %d priced %s, expected %d", bids, got, expected)
+		}
+	}
+}
+
+func TestMarginStrategy(t *testing.T) {
+	strategy := MarginStrategy{Start: big.NewInt(5), Margin: big.NewInt(1), MarginPercent: 10}
+	tests := []struct {
+		state    SlotState
+		expected int64
+	}{
+		{state: SlotState{}, expected: 5},
+		{state: SlotState{PrevWinningTip: big.NewInt(100)}, expected: 111},
+		{state: SlotState{PrevWinningTip: big.NewInt(100), PrevWinnerOurs: true}, expected: 100},
+		{state: SlotState{PrevWinningTip: big.NewInt(100), PrevWinnerOurs: true, RivalPendingTip: big.NewInt(200)}, expected: 221},
+		{state: SlotState{PrevWinningTip: big.NewInt(300), RivalPendingTip: big.NewInt(200)}, expected: 331},
+	}
+	for _, test := range tests {
+		if got := strategy.Bid(test.state); got.Int64() != test.expected {
+			t.Errorf("%+v: bid %s, expected %d", test.state, got, test.expected)
+		}
+	}
+}
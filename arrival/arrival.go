@@ -0,0 +1,165 @@
+package arrival
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	ModelRegular = "regular" // fixed interval 1/rate
+	ModelPoisson = "poisson" // exponential inter-arrival times
+	ModelOnOff   = "onoff"   // poisson arrivals during exponential ON periods, silence during OFF periods
+	ModelPareto  = "pareto"  // heavy-tailed pareto inter-arrival times
+	ModelTrace   = "trace"   // inter-arrival times replayed from trace of real timestamps
+)
+
+func ParseModel(s string) (string, error) {
+	switch s {
+	case ModelRegular, ModelPoisson, ModelOnOff, ModelPareto, ModelTrace:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown arrival model %s, expected regular, poisson, onoff, pareto or trace", s)
+	}
+}
+
+// Process generates inter-arrival times of bids
+type Process interface {
+	Next() time.Duration
+}
+
+// Options are parameters of arrival models, all models except trace keep average rate
+type Options struct {
+	// mean duration of ON and OFF periods of onoff model
+	OnMean  time.Duration
+	OffMean time.Duration
+	// shape of pareto model, must be > 1, lower values give heavier tail
+	ParetoAlpha float64
+
+	// offsets of trace arrivals from the first one, see LoadTrace
+	Trace []time.Duration
+	// agent replays every TraceAgents-th arrival of the trace starting at TraceIndex,
+	// so agents sharing the trace together reproduce it
+	TraceIndex  int
+	TraceAgents int
+}
+
+// New returns arrival process of model with average rate arrivals per second
+func New(model string, rate float64, opts Options, rng *rand.Rand) (Process, error) {
+	if model != ModelTrace && rate <= 0 {
+		return nil, fmt.Errorf("arrival rate must be > 0")
+	}
+	mean := time.Duration(float64(time.Second) / rate)
+	switch model {
+	case ModelRegular:
+		return regular(mean), nil
+	case ModelPoisson:
+		return &poisson{mean: mean, rng: rng}, nil
+	case ModelOnOff:
+		if opts.OnMean <= 0 || opts.OffMean < 0 {
+			return nil, fmt.Errorf("onoff model needs ON period > 0 and OFF period >= 0")
+		}
+		// rate during ON periods is raised so average rate stays the same
+		onMean := time.Duration(float64(mean) * float64(opts.OnMean) / float64(opts.OnMean+opts.OffMean))
+		return &onOff{arrivalMean: onMean, onMean: opts.OnMean, offMean: opts.OffMean, rng: rng}, nil
+	case ModelPareto:
+		if opts.ParetoAlpha <= 1 {
+			return nil, fmt.Errorf("pareto alpha must be > 1, got %f", opts.ParetoAlpha)
+		}
+		// scale that gives the mean interval
+		scale := float64(mean) * (opts.ParetoAlpha - 1) / opts.ParetoAlpha
+		return &pareto{scale: scale, alpha: opts.ParetoAlpha, rng: rng}, nil
+	case ModelTrace:
+		return newTrace(opts.Trace, opts.TraceIndex, opts.TraceAgents)
+	default:
+		return nil, fmt.Errorf("unknown arrival model %s", model)
+	}
+}
+
+type regular time.Duration
+
+func (r regular) Next() time.Duration {
+	return time.Duration(r)
+}
+
+type poisson struct {
+	mean time.Duration
+	rng  *rand.Rand
+}
+
+func (p *poisson) Next() time.Duration {
+	return time.Duration(p.rng.ExpFloat64() * float64(p.mean))
+}
+
+type onOff struct {
+	arrivalMean time.Duration
+	onMean      time.Duration
+	offMean     time.Duration
+	rng         *rand.Rand
+
+	// time left in the current ON period
+	onLeft  time.Duration
+	started bool
+}
+
+func (o *onOff) Next() time.Duration {
+	if !o.started {
+		o.started = true
+		o.onLeft = time.Duration(o.rng.ExpFloat64() * float64(o.onMean))
+	}
+	var wait time.Duration
+	for {
+		next := time.Duration(o.rng.ExpFloat64() * float64(o.arrivalMean))
+		if next <= o.onLeft {
+			o.onLeft -= next
+			return wait + next
+		}
+		// ON period ends before the next arrival, skip OFF period and start the next ON period
+		wait += o.onLeft + time.Duration(o.rng.ExpFloat64()*float64(o.offMean))
+		o.onLeft = time.Duration(o.rng.ExpFloat64() * float64(o.onMean))
+	}
+}
+
+type pareto struct {
+	scale float64
+	alpha float64
+	rng   *rand.Rand
+}
+
+func (p *pareto) Next() time.Duration {
+	u := 1 - p.rng.Float64() // (0, 1]
+	return time.Duration(p.scale / math.Pow(u, 1/p.alpha))
+}
+
+// Pacer waits for arrivals of process on absolute schedule, so time spent between waits doesn't delay arrivals.
+// Schedule is reset if caller falls behind by more than a second.
+type Pacer struct {
+	process Process
+	next    time.Time
+}
+
+func NewPacer(process Process) *Pacer {
+	return &Pacer{process: process}
+}
+
+func (p *Pacer) Wait(ctx context.Context) error {
+	now := time.Now()
+	if p.next.IsZero() || now.Sub(p.next) > time.Second {
+		p.next = now
+	}
+	p.next = p.next.Add(p.process.Next())
+	wait := time.Until(p.next)
+	if wait <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,124 @@
+package arrival
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// sample returns mean and coefficient of variation of n inter-arrival times
+func sample(t *testing.T, process Process, n int) (time.Duration, float64, time.Duration) {
+	t.Helper()
+	var sum, sumSq float64
+	var max time.Duration
+	for i := 0; i < n; i++ {
+		next := process.Next()
+		if next < 0 {
+			t.Fatalf("negative inter-arrival time %s", next)
+		}
+		if next > max {
+			max = next
+		}
+		sum += float64(next)
+		sumSq += float64(next) * float64(next)
+	}
+	mean := sum / float64(n)
+	std := math.Sqrt(sumSq/float64(n) - mean*mean)
+	return time.Duration(mean), std / mean, max
+}
+
+func TestModelsKeepRate(t *testing.T) {
+	tests := []struct {
+		model    string
+		minCV    float64
+		maxCV    float64
+		minRatio float64 // of max interval to mean
+	}{
+		{model: ModelRegular, maxCV: 0.01},
+		{model: ModelPoisson, minCV: 0.9, maxCV: 1.1},
+		{model: ModelOnOff, minCV: 1.5, maxCV: 100},
+		{model: ModelPareto, minCV: 1.5, maxCV: 100, minRatio: 50},
+	}
+	opts := Options{OnMean: 200 * time.Millisecond, OffMean: 800 * time.Millisecond, ParetoAlpha: 1.5}
+	for _, test := range tests {
+		t.Run(test.model, func(t *testing.T) {
+			process, err := New(test.model, 100, opts, rand.New(rand.NewSource(1)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			mean, cv, max := sample(t, process, 200000)
+			// pareto with alpha 1.5 has infinite variance, its sample mean converges slowly
+			if mean < 8*time.Millisecond || mean > 12*time.Millisecond {
+				t.Errorf("mean interval %s, expected 10ms", mean)
+			}
+			if cv < test.minCV || cv > test.maxCV {
+				t.Errorf("coefficient of variation %f, expected in [%f, %f]", cv, test.minCV, test.maxCV)
+			}
+			if ratio := float64(max) / float64(mean); ratio < test.minRatio {
+				t.Errorf("max interval is %f times mean, expected at least %f", ratio, test.minRatio)
+			}
+		})
+	}
+}
+
+func TestTraceAgentsReproduceTrace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.csv")
+	err := os.WriteFile(path, []byte("timestamp,relay\n1700000000.5,a\n1700000000.0,a\n1700000000.25,a\n2023-11-14T22:13:21Z,a\n1700000001.75,a\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offsets, err := LoadTrace(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []time.Duration{0, 250 * time.Millisecond, 500 * time.Millisecond, time.Second, 1750 * time.Millisecond}
+	if len(offsets) != len(expected) {
+		t.Fatalf("loaded %v, expected %v", offsets, expected)
+	}
+	for i := range expected {
+		if offsets[i] != expected[i] {
+			t.Fatalf("loaded %v, expected %v", offsets, expected)
+		}
+	}
+
+	// two loops of the trace by 3 agents
+	var arrivals []time.Duration
+	for agent := 0; agent < 3; agent++ {
+		process, err := New(ModelTrace, 0, Options{Trace: offsets, TraceIndex: agent, TraceAgents: 3}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var at time.Duration
+		for i := agent; i < 2*len(offsets); i += 3 {
+			at += process.Next()
+			arrivals = append(arrivals, at)
+		}
+	}
+	sort.Slice(arrivals, func(i, j int) bool { return arrivals[i] < arrivals[j] })
+	// second loop starts one mean gap after the trace end
+	period := 1750*time.Millisecond + 1750*time.Millisecond/4
+	for i, arrival := range arrivals {
+		want := expected[i%len(expected)] + time.Duration(i/len(expected))*period
+		if arrival != want {
+			t.Errorf("arrival %d at %s, expected %s", i, arrival, want)
+		}
+	}
+}
+
+func TestTraceRejectsSingleInstant(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.csv")
+	err := os.WriteFile(path, []byte("1700000000.5\n1700000000.5\n1700000000.5\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offsets, err := LoadTrace(path); err == nil {
+		t.Errorf("loaded trace %v of equal timestamps", offsets)
+	}
+	if _, err := New(ModelTrace, 0, Options{Trace: []time.Duration{0, 0}, TraceAgents: 1}, nil); err == nil {
+		t.Error("created trace process with zero period")
+	}
+}
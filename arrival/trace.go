@@ -0,0 +1,113 @@
+package arrival
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoadTrace reads timestamps from the first column of csv file and returns their offsets from the earliest one.
+// Timestamps are unix seconds with optional fraction or RFC3339, rows that can't be parsed (e.g. header) are skipped.
+func LoadTrace(path string) ([]time.Duration, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	var timestamps []time.Time
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) == 0 {
+			continue
+		}
+		timestamp, ok := parseTimestamp(strings.TrimSpace(record[0]))
+		if !ok {
+			continue
+		}
+		timestamps = append(timestamps, timestamp)
+	}
+	if len(timestamps) < 2 {
+		return nil, fmt.Errorf("trace %s has %d timestamps, at least 2 needed", path, len(timestamps))
+	}
+
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i].Before(timestamps[j]) })
+	offsets := make([]time.Duration, len(timestamps))
+	for i, timestamp := range timestamps {
+		offsets[i] = timestamp.Sub(timestamps[0])
+	}
+	// trace of a single instant has zero period, replay would never wait
+	if offsets[len(offsets)-1] == 0 {
+		return nil, fmt.Errorf("trace %s has %d equal timestamps, at least 2 distinct needed", path, len(timestamps))
+	}
+	return offsets, nil
+}
+
+func parseTimestamp(s string) (time.Time, bool) {
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		whole, frac := math.Modf(seconds)
+		return time.Unix(int64(whole), int64(frac*1e9)), true
+	}
+	if timestamp, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return timestamp, true
+	}
+	return time.Time{}, false
+}
+
+// trace replays every agents-th arrival of offsets starting at index, looping when trace ends
+type trace struct {
+	offsets []time.Duration
+	// duration of one loop, trace end plus its mean gap
+	period time.Duration
+	agents int
+
+	position int
+	// offset of the last returned arrival
+	last time.Duration
+}
+
+func newTrace(offsets []time.Duration, index, agents int) (*trace, error) {
+	if len(offsets) < 2 {
+		return nil, fmt.Errorf("trace needs at least 2 timestamps")
+	}
+	if agents < 1 || index < 0 || index >= agents {
+		return nil, fmt.Errorf("invalid trace agent %d of %d", index, agents)
+	}
+	end := offsets[len(offsets)-1]
+	if end <= 0 {
+		return nil, fmt.Errorf("trace needs at least 2 distinct timestamps")
+	}
+	return &trace{
+		offsets:  offsets,
+		period:   end + end/time.Duration(len(offsets)-1),
+		agents:   agents,
+		position: index,
+	}, nil
+}
+
+func (t *trace) offset(position int) time.Duration {
+	loop := position / len(t.offsets)
+	return time.Duration(loop)*t.period + t.offsets[position%len(t.offsets)]
+}
+
+func (t *trace) Next() time.Duration {
+	next := t.offset(t.position)
+	wait := next - t.last
+	t.last = next
+	t.position += t.agents
+	return wait
+}
@@ -0,0 +1,225 @@
+package bench
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/metachris/flashbotsrpc"
+	"go-bundles-go/relay"
+	"golang.org/x/time/rate"
+	"math"
+	"math/big"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// minThroughputShare is share of the offered rate a step has to achieve, lower means the relay or the client is saturated
+const minThroughputShare = 0.9
+
+// HeadReader reads the chain head bundles are targeted after
+type HeadReader interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// Bundle is pre-signed bundle and key signing its relay requests
+type Bundle struct {
+	Request  flashbotsrpc.FlashbotsSendBundleRequest
+	RelayKey *ecdsa.PrivateKey
+}
+
+// SLO is target latency and error rate, the knee is the last step meeting it
+type SLO struct {
+	// 0 disables latency target
+	P99Ms     float64 `json:"p99Ms"`
+	ErrorRate float64 `json:"errorRate"`
+}
+
+// Config of load steps: rate and concurrency are multiplied by Factor every step until the SLO is violated
+// or MaxRate is exceeded, then the knee is refined by bisecting rate between the last good and the first bad step
+type Config struct {
+	StartRate        float64
+	StartConcurrency int
+	Factor           float64
+	MaxRate          float64
+	MaxConcurrency   int
+	StepDuration     time.Duration
+	Refine           int
+	SLO              SLO
+}
+
+// StepResult is measurement of one load step, latencies are in milliseconds
+type StepResult struct {
+	Rate         float64           `json:"rate"`
+	Concurrency  int               `json:"concurrency"`
+	Requests     uint64            `json:"requests"`
+	Errors       uint64            `json:"errors"`
+	ErrorRate    float64           `json:"errorRate"`
+	Throughput   float64           `json:"throughput"`
+	P50          float64           `json:"p50Ms"`
+	P99          float64           `json:"p99Ms"`
+	P999         float64           `json:"p999Ms"`
+	ErrorClasses map[string]uint64 `json:"errorClasses,omitempty"`
+	WithinSLO    bool              `json:"withinSlo"`
+}
+
+// Result is comparable across relay builds, Knee is nil if even the first step violated the SLO
+type Result struct {
+	Target string       `json:"target"`
+	Start  time.Time    `json:"start"`
+	SLO    SLO          `json:"slo"`
+	Steps  []StepResult `json:"steps"`
+	Knee   *StepResult  `json:"knee"`
+}
+
+// Bench drives pre-signed bundles at relay
+type Bench struct {
+	relay   relay.Client
+	head    HeadReader
+	bundles []Bundle
+	config  Config
+
+	next atomic.Uint64
+}
+
+// New creates benchmark of relayClient, bundles are retargeted to the block after head of every step
+func New(relayClient relay.Client, head HeadReader, bundles []Bundle, config Config) (*Bench, error) {
+	if len(bundles) == 0 {
+		return nil, fmt.Errorf("bench needs at least one bundle")
+	}
+	if config.StartRate <= 0 || config.StartConcurrency < 1 || config.Factor <= 1 {
+		return nil, fmt.Errorf("bench start rate must be > 0, start concurrency >= 1 and factor > 1")
+	}
+	if config.MaxConcurrency < config.StartConcurrency {
+		config.MaxConcurrency = config.StartConcurrency
+	}
+	return &Bench{relay: relayClient, head: head, bundles: bundles, config: config}, nil
+}
+
+// Run steps load up until the SLO is violated and returns measurements with the knee
+func (b *Bench) Run(ctx context.Context, target string) (Result, error) {
+	result := Result{Target: target, Start: time.Now(), SLO: b.config.SLO}
+
+	var good, bad *StepResult
+	stepRate, concurrency := b.config.StartRate, float64(b.config.StartConcurrency)
+	for b.config.MaxRate <= 0 || stepRate <= b.config.MaxRate {
+		step, err := b.step(ctx, stepRate, b.concurrency(concurrency))
+		if err != nil {
+			return result, err
+		}
+		result.Steps = append(result.Steps, step)
+		if !step.WithinSLO {
+			bad = &step
+			break
+		}
+		good = &step
+		stepRate *= b.config.Factor
+		concurrency *= b.config.Factor
+	}
+
+	if good != nil && bad != nil {
+		for i := 0; i < b.config.Refine; i++ {
+			stepRate := (good.Rate + bad.Rate) / 2
+			step, err := b.step(ctx, stepRate, b.concurrency((float64(good.Concurrency)+float64(bad.Concurrency))/2))
+			if err != nil {
+				return result, err
+			}
+			result.Steps = append(result.Steps, step)
+			if step.WithinSLO {
+				good = &step
+			} else {
+				bad = &step
+			}
+		}
+	}
+	result.Knee = good
+	return result, nil
+}
+
+func (b *Bench) concurrency(c float64) int {
+	return int(math.Min(math.Ceil(c), float64(b.config.MaxConcurrency)))
+}
+
+// step sends bundles at rate with at most concurrency requests in flight for StepDuration
+func (b *Bench) step(ctx context.Context, r float64, concurrency int) (StepResult, error) {
+	header, err := b.head.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return StepResult{}, err
+	}
+	blockNumber := hexutil.EncodeUint64(header.Number.Uint64() + 1)
+
+	stepCtx, cancel := context.WithTimeout(ctx, b.config.StepDuration)
+	defer cancel()
+	limiter := rate.NewLimiter(rate.Limit(r), 1)
+	stats := relay.NewStats()
+
+	var mu sync.Mutex
+	var latencies []time.Duration
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for limiter.Wait(stepCtx) == nil {
+				bundle := b.bundles[(b.next.Add(1)-1)%uint64(len(b.bundles))]
+				request := bundle.Request
+				// only the request is retargeted, the signed tx still targets presign block and reverts after it
+				request.BlockNumber = blockNumber
+				sent := time.Now()
+				_, err := b.relay.FlashbotsSendBundle(bundle.RelayKey, request)
+				latency := time.Since(sent)
+				stats.Record(err)
+				if err == nil {
+					mu.Lock()
+					latencies = append(latencies, latency)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	if ctx.Err() != nil {
+		return StepResult{}, ctx.Err()
+	}
+
+	step := StepResult{Rate: r, Concurrency: concurrency, ErrorClasses: make(map[string]uint64)}
+	for _, class := range relay.Classes {
+		if count := stats.Count(class); count > 0 {
+			step.ErrorClasses[string(class)] = count
+			step.Errors += count
+		}
+	}
+	step.Requests = stats.Ok() + step.Errors
+	if step.Requests > 0 {
+		step.ErrorRate = float64(step.Errors) / float64(step.Requests)
+	}
+	step.Throughput = float64(step.Requests) / elapsed.Seconds()
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	step.P50 = percentile(latencies, 0.5)
+	step.P99 = percentile(latencies, 0.99)
+	step.P999 = percentile(latencies, 0.999)
+	step.WithinSLO = step.ErrorRate <= b.config.SLO.ErrorRate &&
+		(b.config.SLO.P99Ms <= 0 || step.P99 <= b.config.SLO.P99Ms) &&
+		step.Throughput >= r*minThroughputShare && len(latencies) > 0
+
+	fmt.Println("bench step", "rate", r, "concurrency", concurrency, "requests", step.Requests, "throughput", fmt.Sprintf("%.1f", step.Throughput),
+		"p50(ms)", step.P50, "p99(ms)", step.P99, "p999(ms)", step.P999, "errorRate", step.ErrorRate, "withinSlo", step.WithinSLO)
+	return step, nil
+}
+
+// percentile returns nearest rank percentile of sorted latencies in milliseconds
+func percentile(sorted []time.Duration, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return float64(sorted[rank]) / float64(time.Millisecond)
+}
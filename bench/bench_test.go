@@ -0,0 +1,121 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/metachris/flashbotsrpc"
+	"go-bundles-go/relay"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fixedHead uint64
+
+func (h fixedHead) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: new(big.Int).SetUint64(uint64(h))}, nil
+}
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 1000; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	for p, expected := range map[float64]float64{0.5: 500, 0.99: 990, 0.999: 999} {
+		if got := percentile(latencies, p); got != expected {
+			t.Errorf("p%v %vms, expected %vms", p*100, got, expected)
+		}
+	}
+	if got := percentile(nil, 0.99); got != 0 {
+		t.Errorf("percentile of no latencies %v", got)
+	}
+}
+
+func TestBenchFindsKnee(t *testing.T) {
+	// relay handles 4 requests at once and fails requests above that
+	var inFlight atomic.Int64
+	var blocks atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Params []flashbotsrpc.FlashbotsSendBundleRequest `json:"params"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Params) == 1 {
+			blocks.Store(req.Params[0].BlockNumber)
+		}
+		if inFlight.Add(1) > 4 {
+			inFlight.Add(-1)
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+		inFlight.Add(-1)
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"bundleHash":"0x01"}}`))
+	}))
+	defer server.Close()
+
+	key, _ := crypto.GenerateKey()
+	bundles := []Bundle{{Request: flashbotsrpc.FlashbotsSendBundleRequest{Txs: []string{"0x00"}, BlockNumber: "0x1"}, RelayKey: key}}
+	b, err := New(relay.NewHTTPClient(server.URL, time.Second), fixedHead(99), bundles, Config{
+		StartRate:        50,
+		StartConcurrency: 1,
+		Factor:           4,
+		MaxConcurrency:   64,
+		StepDuration:     300 * time.Millisecond,
+		Refine:           1,
+		SLO:              SLO{P99Ms: 100, ErrorRate: 0.01},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := b.Run(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if blocks.Load() != "0x64" {
+		t.Errorf("bundles target %v, expected block after head 0x64", blocks.Load())
+	}
+	if len(result.Steps) < 3 {
+		t.Fatalf("%d steps, expected ramp up, violation and refinement", len(result.Steps))
+	}
+	if result.Knee == nil {
+		t.Fatal("knee not found")
+	}
+	if !result.Knee.WithinSLO || result.Knee.ErrorRate > 0.01 || result.Knee.Rate < 200 {
+		t.Errorf("knee %+v, expected step within SLO at 200/s or more", *result.Knee)
+	}
+	violated := false
+	for _, step := range result.Steps {
+		if !step.WithinSLO {
+			violated = true
+			if step.Rate <= result.Knee.Rate {
+				t.Errorf("step at rate %v violated SLO below knee at %v", step.Rate, result.Knee.Rate)
+			}
+		}
+		if step.Requests == 0 || step.P50 > step.P99 || step.P99 > step.P999 {
+			t.Errorf("step %+v has inconsistent measurements", step)
+		}
+	}
+	if !violated {
+		t.Error("no step violated SLO")
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Result
+	err = json.Unmarshal(data, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Knee == nil || decoded.Knee.Rate != result.Knee.Rate || len(decoded.Steps) != len(result.Steps) {
+		t.Errorf("json result %s doesn't round trip", data)
+	}
+}
@@ -0,0 +1,80 @@
+package bench
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/metachris/flashbotsrpc"
+	"go-bundles-go/chain"
+	"go-bundles-go/contracts/mevsim"
+	"go-bundles-go/wallet"
+	"math/big"
+)
+
+// Presign signs count single auction tx bundles for the block after the current head, taking turns of signers.
+// Every bundle pays tip plus its index in wei so all bundles have different hashes.
+// Signing is done before the benchmark so it doesn't count towards latency.
+// Calldata target block, slot value and nonces are those of presign time, so bundles revert once the head moves on
+// even though requests are retargeted: the benchmark measures acceptance, not simulation or inclusion.
+func Presign(ctx context.Context, client chain.Backend, chainID *big.Int, signers []wallet.TxSigner, mevsimAddr common.Address, slot *big.Int, count int, gasLimit uint64, tip *big.Int) ([]Bundle, error) {
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("presigning needs at least one signer")
+	}
+	mevsimAbi, err := mevsim.MevSimMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	caller, err := mevsim.NewMevSimCaller(mevsimAddr, client)
+	if err != nil {
+		return nil, err
+	}
+	header, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	targetBlock := header.Number.Uint64() + 1
+	slotValue, err := caller.GetSlot(&bind.CallOpts{Context: ctx}, slot)
+	if err != nil {
+		return nil, err
+	}
+	data, err := mevsimAbi.Pack("auction", slot, slotValue, new(big.Int).SetUint64(targetBlock))
+	if err != nil {
+		return nil, err
+	}
+
+	nonces := make([]uint64, len(signers))
+	relayKeys := make([]*ecdsa.PrivateKey, len(signers))
+	for i, signer := range signers {
+		nonces[i], err = client.PendingNonceAt(ctx, signer.Address())
+		if err != nil {
+			return nil, err
+		}
+		relayKeys[i], err = wallet.RelayKey(signer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	bundles := make([]Bundle, count)
+	for i := range bundles {
+		signer := signers[i%len(signers)]
+		effGasPrice := new(big.Int).Add(tip, big.NewInt(int64(i)))
+		tx, err := signer.SignTx(chain.NewBidTx(types.DynamicFeeTxType, chainID, nonces[i%len(signers)], mevsimAddr, gasLimit, header.BaseFee, effGasPrice, big.NewInt(0), data, nil), chainID)
+		if err != nil {
+			return nil, err
+		}
+		txBytes, err := tx.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		bundles[i] = Bundle{
+			Request:  flashbotsrpc.FlashbotsSendBundleRequest{Txs: []string{hexutil.Encode(txBytes)}, BlockNumber: hexutil.EncodeUint64(targetBlock)},
+			RelayKey: relayKeys[i%len(signers)],
+		}
+	}
+	return bundles, nil
+}
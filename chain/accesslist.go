@@ -0,0 +1,46 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+)
+
+// CreateAccessList calls eth_createAccessList for msg on pending state
+func CreateAccessList(ctx context.Context, client *gethrpc.Client, msg ethereum.CallMsg) (types.AccessList, error) {
+	accessList, _, vmErr, err := gethclient.New(client).CreateAccessList(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	if vmErr != "" {
+		return nil, fmt.Errorf("access list call failed: %s", vmErr)
+	}
+	return *accessList, nil
+}
+
+// EstimateGasWithAccessList is like ethclient.EstimateGas but also passes msg.AccessList to the node
+func EstimateGasWithAccessList(ctx context.Context, client *gethrpc.Client, msg ethereum.CallMsg) (uint64, error) {
+	arg := map[string]interface{}{
+		"from": msg.From,
+		"to":   msg.To,
+	}
+	if len(msg.Data) > 0 {
+		arg["data"] = hexutil.Bytes(msg.Data)
+	}
+	if msg.Value != nil {
+		arg["value"] = (*hexutil.Big)(msg.Value)
+	}
+	if msg.AccessList != nil {
+		arg["accessList"] = msg.AccessList
+	}
+	var gas hexutil.Uint64
+	err := client.CallContext(ctx, &gas, "eth_estimateGas", arg)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(gas), nil
+}
@@ -0,0 +1,74 @@
+package chain
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"math/big"
+	"net/http"
+	"strings"
+)
+
+// Backend is node api used by agents, implemented by *NodeBackend and simulated backend
+type Backend interface {
+	bind.ContractBackend
+}
+
+// TxBackend is node api used to send transactions and wait until they are mined,
+// implemented by *ethclient.Client and *NodeBackend
+type TxBackend interface {
+	bind.ContractBackend
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	ChainID(ctx context.Context) (*big.Int, error)
+}
+
+// AccessListBackend is implemented by chain backends that can create access lists
+// and estimate gas of calls with access list
+type AccessListBackend interface {
+	CreateAccessList(ctx context.Context, msg ethereum.CallMsg) (types.AccessList, error)
+	EstimateGasWithAccessList(ctx context.Context, msg ethereum.CallMsg) (uint64, error)
+}
+
+// NodeBackend is ethclient connected to the node over json rpc
+type NodeBackend struct {
+	*ethclient.Client
+	rpcClient *gethrpc.Client
+}
+
+func Dial(rpc string) (*NodeBackend, error) {
+	rpcClient, err := gethrpc.Dial(rpc)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeBackend{Client: ethclient.NewClient(rpcClient), rpcClient: rpcClient}, nil
+}
+
+// DialWithClient connects to http rpc using httpClient, other rpc urls such as websocket are dialed as by Dial
+func DialWithClient(rpc string, httpClient *http.Client) (*NodeBackend, error) {
+	if !strings.HasPrefix(rpc, "http://") && !strings.HasPrefix(rpc, "https://") {
+		return Dial(rpc)
+	}
+	rpcClient, err := gethrpc.DialHTTPWithClient(rpc, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeBackend{Client: ethclient.NewClient(rpcClient), rpcClient: rpcClient}, nil
+}
+
+func (n *NodeBackend) CreateAccessList(ctx context.Context, msg ethereum.CallMsg) (types.AccessList, error) {
+	return CreateAccessList(ctx, n.rpcClient, msg)
+}
+
+func (n *NodeBackend) EstimateGasWithAccessList(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	return EstimateGasWithAccessList(ctx, n.rpcClient, msg)
+}
+
+// BatchCallContext sends json-rpc calls in a single batch request
+func (n *NodeBackend) BatchCallContext(ctx context.Context, b []gethrpc.BatchElem) error {
+	return n.rpcClient.BatchCallContext(ctx, b)
+}
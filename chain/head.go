@@ -0,0 +1,83 @@
+package chain
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// headHistory is number of recent blocks HeadTracker remembers hashes of
+const headHistory = 128
+
+// Reorg is replacement of the head by a block that doesn't extend it
+type Reorg struct {
+	// number of blocks of the old chain that were replaced, a lower bound if the fork point isn't known
+	Depth     uint64
+	OldNumber uint64
+	OldHash   common.Hash
+	NewNumber uint64
+	NewHash   common.Hash
+}
+
+// HeadTracker follows the head by hashes, so that reorgs and uncles at the same height are told from new blocks
+type HeadTracker struct {
+	head   *types.Header
+	hashes map[uint64]common.Hash
+}
+
+func NewHeadTracker() *HeadTracker {
+	return &HeadTracker{hashes: make(map[uint64]common.Hash)}
+}
+
+// Head returns the last accepted header, nil before the first one
+func (t *HeadTracker) Head() *types.Header {
+	return t.head
+}
+
+// Compare reports whether header is a different block than the head and the reorg if header doesn't extend the head.
+// The head isn't changed until header is accepted.
+func (t *HeadTracker) Compare(header *types.Header) (bool, *Reorg) {
+	if t.head == nil {
+		return true, nil
+	}
+	hash := header.Hash()
+	if hash == t.head.Hash() {
+		return false, nil
+	}
+	number := header.Number.Uint64()
+	headNumber := t.head.Number.Uint64()
+	if number > headNumber+1 {
+		// blocks in between weren't seen, a reorg among them can't be told
+		return true, nil
+	}
+	if number == headNumber+1 && header.ParentHash == t.head.Hash() {
+		return true, nil
+	}
+
+	reorg := &Reorg{OldNumber: headNumber, OldHash: t.head.Hash(), NewNumber: number, NewHash: hash}
+	// the old chain is replaced above the fork point, parent of header if its hash is known
+	reorg.Depth = headNumber + 1 - number
+	if number == 0 || t.hashes[number-1] != header.ParentHash {
+		reorg.Depth++
+	}
+	return true, reorg
+}
+
+// Accept makes header the head, hashes of replaced blocks above it are forgotten
+func (t *HeadTracker) Accept(header *types.Header) {
+	number := header.Number.Uint64()
+	if t.head != nil {
+		for n := number + 1; n <= t.head.Number.Uint64(); n++ {
+			delete(t.hashes, n)
+		}
+	}
+	t.head = header
+	t.hashes[number] = header.Hash()
+	if number > 0 {
+		t.hashes[number-1] = header.ParentHash
+	}
+	for n := range t.hashes {
+		if n+headHistory <= number {
+			delete(t.hashes, n)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package chain
+
+import (
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"testing"
+)
+
+// headers returns chain of blocks 0..n, fork tells apart chains with the same numbers.
+// Blocks below from are taken from parent chain.
+func headers(n uint64, fork byte, parent []*types.Header, from uint64) []*types.Header {
+	var chain []*types.Header
+	for i := uint64(0); i <= n; i++ {
+		if i < from {
+			chain = append(chain, parent[i])
+			continue
+		}
+		header := &types.Header{Number: new(big.Int).SetUint64(i), Extra: []byte{fork}}
+		if i > 0 {
+			header.ParentHash = chain[i-1].Hash()
+		}
+		chain = append(chain, header)
+	}
+	return chain
+}
+
+func TestHeadTrackerDetectsReorgs(t *testing.T) {
+	canonical := headers(300, 0, nil, 0)
+	// side chains forked after block 9 and after block 1
+	side := headers(300, 1, canonical, 10)
+	deep := headers(300, 2, canonical, 2)
+
+	tests := []struct {
+		name     string
+		accepted []*types.Header
+		header   *types.Header
+		changed  bool
+		// 0 if header isn't a reorg
+		depth uint64
+	}{
+		{name: "same head", accepted: canonical[1:11], header: canonical[10], changed: false},
+		{name: "extension", accepted: canonical[1:11], header: canonical[11], changed: true},
+		{name: "uncle at the same height", accepted: canonical[1:11], header: side[10], changed: true, depth: 1},
+		{name: "1-deep reorg to a longer chain", accepted: canonical[1:11], header: side[11], changed: true, depth: 1},
+		{name: "2-deep reorg to a lower head", accepted: canonical[1:12], header: side[10], changed: true, depth: 2},
+		// fork point is beyond history, depth is the lower bound
+		{name: "reorg deeper than history", accepted: canonical[1:201], header: deep[50], changed: true, depth: 152},
+		{name: "gap in block numbers", accepted: canonical[1:11], header: side[15], changed: true},
+		{name: "first head", header: canonical[5], changed: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracker := NewHeadTracker()
+			for _, header := range test.accepted {
+				tracker.Accept(header)
+			}
+			changed, reorg := tracker.Compare(test.header)
+			if changed != test.changed {
+				t.Errorf("changed %v, expected %v", changed, test.changed)
+			}
+			if test.depth == 0 {
+				if reorg != nil {
+					t.Errorf("reorg %+v, expected none", reorg)
+				}
+				return
+			}
+			if reorg == nil {
+				t.Fatal("reorg not detected")
+			}
+			head := test.accepted[len(test.accepted)-1]
+			if reorg.Depth != test.depth || reorg.OldNumber != head.Number.Uint64() || reorg.OldHash != head.Hash() ||
+				reorg.NewNumber != test.header.Number.Uint64() || reorg.NewHash != test.header.Hash() {
+				t.Errorf("reorg %+v, expected depth %d from %d to %d", reorg, test.depth, head.Number, test.header.Number)
+			}
+		})
+	}
+
+	// hashes of replaced blocks are forgotten, the new chain is followed
+	tracker := NewHeadTracker()
+	for _, header := range canonical[1:11] {
+		tracker.Accept(header)
+	}
+	tracker.Accept(side[10])
+	if changed, reorg := tracker.Compare(side[11]); !changed || reorg != nil {
+		t.Errorf("extension of the new chain: changed %v reorg %+v", changed, reorg)
+	}
+	if _, reorg := tracker.Compare(canonical[11]); reorg == nil || reorg.Depth != 1 {
+		t.Errorf("block of the replaced chain: reorg %+v, expected depth 1", reorg)
+	}
+	if len(tracker.hashes) > headHistory {
+		t.Errorf("tracker keeps %d hashes, expected at most %d", len(tracker.hashes), headHistory)
+	}
+}
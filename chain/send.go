@@ -0,0 +1,95 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"go-bundles-go/wallet"
+	"math/big"
+)
+
+// SendAndWait sends tx with data to address (nil for contract creation) and waits until it's mined successfully
+func SendAndWait(ctx context.Context, client TxBackend, chainId *big.Int, deployerWallet wallet.TxSigner, to *common.Address, data []byte, gasLimit uint64, gasMultiplier float64) (*types.Receipt, error) {
+	// deployer address
+	deployer := deployerWallet.Address()
+
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	priorityFee, err := client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deployerBalance, err := client.BalanceAt(ctx, deployer, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if gasLimit == 0 {
+		estimatedGas, err := client.EstimateGas(ctx, ethereum.CallMsg{From: deployer, To: to, Data: data})
+		if err != nil {
+			return nil, err
+		}
+		gasLimit = ApplyGasMultiplier(estimatedGas, gasMultiplier)
+	}
+
+	// deployer balance in eth
+	fee := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gasLimit))
+
+	fmt.Println("balance", WeiToUnit(deployerBalance, 1e18),
+		"fee", WeiToUnit(fee, 1e18),
+		"gasLimit", gasLimit,
+		"gasPrice(gwei)", WeiToUnit(gasPrice, 1e9),
+		"priorityFee(gwei)", WeiToUnit(priorityFee, 1e9))
+
+	// check if deployer has enough balance
+	if deployerBalance.Cmp(fee) < 0 {
+		return nil, fmt.Errorf("insufficient balance")
+	}
+
+	nonce, err := client.PendingNonceAt(ctx, deployer)
+	if err != nil {
+		return nil, err
+	}
+
+	// create and sign transaction
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainId,
+		Nonce:     nonce,
+		GasTipCap: priorityFee,
+		GasFeeCap: gasPrice,
+		Gas:       gasLimit,
+		To:        to,
+		Data:      data,
+	})
+
+	signedTx, err := deployerWallet.SignTx(tx, chainId)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Println("tx hash", signedTx.Hash().Hex())
+
+	// send transaction
+	err = client.SendTransaction(ctx, signedTx)
+	if err != nil {
+		return nil, err
+	}
+
+	// wait for transaction to be mined
+	receipt, err := bind.WaitMined(ctx, client, signedTx)
+	if err != nil {
+		return nil, err
+	}
+
+	// check if transaction was successful
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, fmt.Errorf("transaction failed")
+	}
+	return receipt, nil
+}
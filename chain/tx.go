@@ -0,0 +1,77 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"math/big"
+)
+
+// ParseTxType parses transaction type name: legacy, access-list or dynamic-fee
+func ParseTxType(s string) (uint8, error) {
+	switch s {
+	case "legacy":
+		return types.LegacyTxType, nil
+	case "access-list":
+		return types.AccessListTxType, nil
+	case "dynamic-fee":
+		return types.DynamicFeeTxType, nil
+	default:
+		return 0, fmt.Errorf("unknown tx type %s, expected legacy, access-list or dynamic-fee", s)
+	}
+}
+
+// NewBidTx creates unsigned transaction of given type that pays effGasPrice over baseFee per gas.
+// Legacy and access list transactions have gas price baseFee + effGasPrice,
+// dynamic fee transactions have the same fee cap and effGasPrice as tip.
+func NewBidTx(txType uint8, chainID *big.Int, nonce uint64, to common.Address, gas uint64, baseFee, effGasPrice, value *big.Int, data []byte, accessList types.AccessList) *types.Transaction {
+	gasPrice := new(big.Int).Add(baseFee, effGasPrice)
+	switch txType {
+	case types.LegacyTxType:
+		return types.NewTx(&types.LegacyTx{
+			Nonce:    nonce,
+			GasPrice: gasPrice,
+			Gas:      gas,
+			To:       &to,
+			Value:    value,
+			Data:     data,
+		})
+	case types.AccessListTxType:
+		return types.NewTx(&types.AccessListTx{
+			ChainID:    chainID,
+			Nonce:      nonce,
+			GasPrice:   gasPrice,
+			Gas:        gas,
+			To:         &to,
+			Value:      value,
+			Data:       data,
+			AccessList: accessList,
+		})
+	default:
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:    chainID,
+			Nonce:      nonce,
+			GasTipCap:  new(big.Int).Set(effGasPrice),
+			GasFeeCap:  gasPrice,
+			Gas:        gas,
+			To:         &to,
+			Value:      value,
+			Data:       data,
+			AccessList: accessList,
+		})
+	}
+}
+
+// VerifyChainID returns chain id reported by eth_chainId, it fails if expected is not 0 and doesn't match
+func VerifyChainID(ctx context.Context, client *ethclient.Client, expected uint64) (*big.Int, error) {
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if expected != 0 && (!chainID.IsUint64() || chainID.Uint64() != expected) {
+		return nil, fmt.Errorf("chain id mismatch, expected %d, node reports %s", expected, chainID.String())
+	}
+	return chainID, nil
+}
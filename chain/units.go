@@ -0,0 +1,15 @@
+package chain
+
+import (
+	"math"
+	"math/big"
+)
+
+func WeiToUnit(wei *big.Int, unit int) *big.Float {
+	return new(big.Float).Quo(new(big.Float).SetInt(wei), new(big.Float).SetInt(big.NewInt(int64(unit))))
+}
+
+// ApplyGasMultiplier scales estimated gas by safety multiplier
+func ApplyGasMultiplier(gas uint64, multiplier float64) uint64 {
+	return uint64(math.Ceil(float64(gas) * multiplier))
+}
@@ -0,0 +1,114 @@
+package create2
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"go-bundles-go/chain"
+	"go-bundles-go/funding"
+	"go-bundles-go/wallet"
+	"math/big"
+)
+
+// Deterministic deployment proxy (https://github.com/Arachnid/deterministic-deployment-proxy).
+// Factory is deployed with presigned pre-EIP-155 transaction so it has the same address on every chain.
+// Calldata of factory call is salt followed by init code.
+var (
+	FactoryAddress  = common.HexToAddress("0x4e59b44847b379578588920ca78fbf26c0b4956c")
+	FactoryDeployer = common.HexToAddress("0x3fab184622dc19b6109349b94811493bf2a45362")
+	FactoryDeployTx = common.FromHex("0xf8a58085174876e800830186a08080b853604580600e600039806000f350fe7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe03601600081602082378035828234f58015156039578182fd5b8082525050506014600cf31ba02222222222222222222222222222222222222222222222222222222222222222a02222222222222222222222222222222222222222222222222222222222222222")
+)
+
+// Address returns address of initCode deployed with salt through create2 factory
+func Address(initCode []byte, salt common.Hash) common.Address {
+	return crypto.CreateAddress2(FactoryAddress, salt, crypto.Keccak256(initCode))
+}
+
+// EnsureFactory deploys create2 factory if it's not on chain yet.
+// Factory deployer is funded from fundingWallet.
+func EnsureFactory(ctx context.Context, client chain.TxBackend, fundingWallet wallet.TxSigner) error {
+	code, err := client.CodeAt(ctx, FactoryAddress, nil)
+	if err != nil {
+		return err
+	}
+	if len(code) > 0 {
+		return nil
+	}
+
+	deployTx := new(types.Transaction)
+	err = deployTx.UnmarshalBinary(FactoryDeployTx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("deploying create2 factory", FactoryAddress.Hex())
+	deployCost := new(big.Int).Mul(deployTx.GasPrice(), new(big.Int).SetUint64(deployTx.Gas()))
+	fundAmounts, _, err := funding.Amounts(ctx, client, []common.Address{FactoryDeployer}, deployCost)
+	if err != nil {
+		return err
+	}
+	sentTxs, err := funding.Send(ctx, client, fundingWallet, []common.Address{FactoryDeployer}, fundAmounts)
+	if err != nil {
+		return err
+	}
+	if len(sentTxs) > 0 {
+		_, err = bind.WaitMined(ctx, client, sentTxs[0])
+		if err != nil {
+			return err
+		}
+	}
+
+	err = client.SendTransaction(ctx, deployTx)
+	if err != nil {
+		return fmt.Errorf("failed to send create2 factory deploy tx, node must accept pre-EIP-155 txs (geth --rpc.allow-unprotected-txs): %w", err)
+	}
+	receipt, err := bind.WaitMined(ctx, client, deployTx)
+	if err != nil {
+		return err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return fmt.Errorf("create2 factory deploy tx failed")
+	}
+	return nil
+}
+
+// Deploy deploys initCode with salt through create2 factory.
+// Deployment is skipped if there is code at the target address already.
+func Deploy(ctx context.Context, client chain.TxBackend, chainID *big.Int, deployerWallet wallet.TxSigner, initCode []byte, salt common.Hash, gasLimit uint64, gasMultiplier float64) (common.Address, error) {
+	if len(initCode) == 0 {
+		return common.Address{}, fmt.Errorf("bytecode is empty")
+	}
+
+	contractAddress := Address(initCode, salt)
+	code, err := client.CodeAt(ctx, contractAddress, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(code) > 0 {
+		fmt.Println("contract already deployed", contractAddress.Hex())
+		return contractAddress, nil
+	}
+
+	err = EnsureFactory(ctx, client, deployerWallet)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	_, err = chain.SendAndWait(ctx, client, chainID, deployerWallet, &FactoryAddress, append(salt.Bytes(), initCode...), gasLimit, gasMultiplier)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	code, err = client.CodeAt(ctx, contractAddress, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(code) == 0 {
+		return common.Address{}, fmt.Errorf("no code at %s after deployment", contractAddress.Hex())
+	}
+	fmt.Println("contract address", contractAddress.Hex())
+	return contractAddress, nil
+}
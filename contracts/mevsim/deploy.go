@@ -0,0 +1,52 @@
+package mevsim
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"go-bundles-go/chain"
+	"go-bundles-go/contracts/create2"
+	"go-bundles-go/wallet"
+	"math/big"
+)
+
+var (
+	Bytecode = common.Hex2Bytes("60808060405234610016576102fd908161001c8239f35b600080fdfe608060405260048036101561001357600080fd5b600090813560e01c806316ea2c57146101045780637eba7ba6146100e5578063b73e7399146100c35763ec7ffc601461004b57600080fd5b60a03660031901126100bf576064359060843567ffffffffffffffff8082116100bb57366023830112156100bb57818301359081116100bb57369101602401116100b7576100a1906044359060243590356101b8565b5a5b816100ae5a83610195565b106100a3578280f35b8280fd5b8480fd5b5080fd5b5060603660031901126100bf576100e2906044359060243590356101b8565b80f35b50346100bf5760203660031901126100bf576020903554604051908152f35b50346100bf5760203660031901126100bf5760409182913581528060205220602061012d61015f565b91546001600160a01b03811680845260a09190911c67ffffffffffffffff169190920181905282519182526020820152f35b604051906040820182811067ffffffffffffffff82111761017f57604052565b634e487b7160e01b600052604160045260246000fd5b919082039182116101a257565b634e487b7160e01b600052601160045260246000fd5b909143036102b557818154036102a357600182018092116101a2578181556101de61015f565b338152602081019067ffffffffffffffff4316825260009183835282602052604083209160018060a01b0390511682549167ffffffffffffffff60a01b905160a01b169163ffffffff60e01b161717905547908080838015610299575b8280929181924190f11561028d5750610254483a610195565b90604051938452602084015260408301527f521bae00645073fde082992240b58241ae62c489d9d29618325eaba160c7733560603393a3565b604051903d90823e3d90fd5b6108fc915061023b565b6040516301b6e1e760e21b8152600490fd5b6040516341f833ab60e11b8152600490fdfea2646970667358221220d47e19596a2af10176d84423109ae8b6a7f06c1d5c0273ffa5cac9973dbf65d364736f6c63430008150033")
+
+	Salt = common.Hash{}
+)
+
+// Address returns address of MevSim deployed through create2 factory with Salt
+func Address() common.Address {
+	return create2.Address(Bytecode, Salt)
+}
+
+// Deploy deploys MevSim from deployerWallet with plain create, gasLimit 0 means estimate and apply gasMultiplier
+func Deploy(ctx context.Context, client chain.TxBackend, chainID *big.Int, deployerWallet wallet.TxSigner, gasLimit uint64, gasMultiplier float64) (common.Address, error) {
+	receipt, err := chain.SendAndWait(ctx, client, chainID, deployerWallet, nil, Bytecode, gasLimit, gasMultiplier)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	// get contract address
+	contractAddress := receipt.ContractAddress
+	fmt.Println("contract address", contractAddress.Hex())
+	return contractAddress, nil
+}
+
+// DeployCreate2 deploys MevSim to Address(), deployment is skipped if it's there already
+func DeployCreate2(ctx context.Context, client chain.TxBackend, chainID *big.Int, deployerWallet wallet.TxSigner, gasLimit uint64, gasMultiplier float64) (common.Address, error) {
+	return create2.Deploy(ctx, client, chainID, deployerWallet, Bytecode, Salt, gasLimit, gasMultiplier)
+}
+
+// StaticAuctionAccessList returns access list of the state touched by auction call: bid slot value,
+// its winner in winners mapping at storage slot 0 and coinbase
+func StaticAuctionAccessList(mevsimAddr common.Address, slot *big.Int, coinbase common.Address) types.AccessList {
+	winnerKey := crypto.Keccak256Hash(common.BigToHash(slot).Bytes(), common.Hash{}.Bytes())
+	return types.AccessList{
+		{Address: mevsimAddr, StorageKeys: []common.Hash{common.BigToHash(slot), winnerKey}},
+		{Address: coinbase, StorageKeys: []common.Hash{}},
+	}
+}
@@ -0,0 +1,100 @@
+package mevsim
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/core"
+	"go-bundles-go/contracts/create2"
+	"go-bundles-go/internal/simchain"
+	"go-bundles-go/wallet"
+	"math/big"
+	"testing"
+)
+
+// newSimChain returns simulated chain without the create2 factory and funded deployer wallet
+func newSimChain(t *testing.T) (*simchain.Backend, wallet.TxSigner) {
+	t.Helper()
+	key, address := simchain.NewKey(t)
+	balance := new(big.Int).Mul(big.NewInt(100), big.NewInt(1e18))
+	backend := simchain.New(t, core.GenesisAlloc{address: {Balance: balance}})
+	backend.AutoMine = true
+	return backend, wallet.NewKeySigner(key)
+}
+
+func TestEnsureFactoryDeploysProxy(t *testing.T) {
+	backend, deployer := newSimChain(t)
+	ctx := context.Background()
+
+	code, err := backend.CodeAt(ctx, create2.FactoryAddress, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) != 0 {
+		t.Fatal("factory is on chain before deployment")
+	}
+
+	err = create2.EnsureFactory(ctx, backend, deployer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err = backend.CodeAt(ctx, create2.FactoryAddress, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) == 0 {
+		t.Fatal("no factory code after deployment")
+	}
+
+	// factory is there already so nothing is sent
+	blockNumber := backend.Blockchain().CurrentBlock().NumberU64()
+	err = create2.EnsureFactory(ctx, backend, deployer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := backend.Blockchain().CurrentBlock().NumberU64(); n != blockNumber {
+		t.Errorf("%d blocks mined by second EnsureFactory", n-blockNumber)
+	}
+}
+
+func TestDeployCreate2IsIdempotent(t *testing.T) {
+	backend, deployer := newSimChain(t)
+	ctx := context.Background()
+	chainID := backend.Blockchain().Config().ChainID
+
+	address, err := DeployCreate2(ctx, backend, chainID, deployer, 0, 1.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address != Address() {
+		t.Fatalf("deployed to %s, expected %s", address.Hex(), Address().Hex())
+	}
+	code, err := backend.CodeAt(ctx, address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) == 0 {
+		t.Fatal("no MevSim code after deployment")
+	}
+
+	blockNumber := backend.Blockchain().CurrentBlock().NumberU64()
+	nonce, err := backend.PendingNonceAt(ctx, deployer.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	address, err = DeployCreate2(ctx, backend, chainID, deployer, 0, 1.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address != Address() {
+		t.Errorf("second deployment returned %s, expected %s", address.Hex(), Address().Hex())
+	}
+	if n := backend.Blockchain().CurrentBlock().NumberU64(); n != blockNumber {
+		t.Errorf("%d blocks mined by second deployment", n-blockNumber)
+	}
+	secondNonce, err := backend.PendingNonceAt(ctx, deployer.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secondNonce != nonce {
+		t.Errorf("deployer sent %d txs on second deployment", secondNonce-nonce)
+	}
+}
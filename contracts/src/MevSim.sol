@@ -4,6 +4,16 @@ contract MevSim {
     error BlockMismatch();
     error SlotValueMismatch();
 
+    event Auctioned(uint256 indexed slot, uint256 newValue, address indexed bidder, uint256 coinbasePaid, uint256 tipPerGas);
+
+    struct Winner {
+        address bidder;
+        uint64 blockNumber;
+    }
+
+    // last winner of each slot, mapping entries are stored at hashed keys so they don't collide with raw slots
+    mapping(uint256 => Winner) private winners;
+
     function getSlot(uint256 slot) public view returns (uint256) {
         uint256 value;
         assembly {
@@ -12,7 +22,24 @@ contract MevSim {
         return value;
     }
 
+    function getLastWinner(uint256 slot) public view returns (address bidder, uint256 blockNumber) {
+        Winner memory winner = winners[slot];
+        return (winner.bidder, winner.blockNumber);
+    }
+
     function auction(uint256 slot, uint256 value, uint256 target_block) public payable {
+        _auction(slot, value, target_block);
+    }
+
+    // same as auction but also burns burn_gas gas, padding is only there to make calldata larger
+    function auctionWithBurn(uint256 slot, uint256 value, uint256 target_block, uint256 burn_gas, bytes calldata padding) public payable {
+        _auction(slot, value, target_block);
+
+        uint256 start = gasleft();
+        while (start - gasleft() < burn_gas) {}
+    }
+
+    function _auction(uint256 slot, uint256 value, uint256 target_block) internal {
         // check target_block
         if (block.number != target_block) {
             revert BlockMismatch();
@@ -29,8 +56,13 @@ contract MevSim {
             sstore(slot, new_value)
         }
 
+        winners[slot] = Winner(msg.sender, uint64(block.number));
+
         // send all eth to coinbase
+        uint256 coinbasePaid = address(this).balance;
         address payable coinbase = payable(block.coinbase);
-        coinbase.transfer(address(this).balance);
+        coinbase.transfer(coinbasePaid);
+
+        emit Auctioned(slot, new_value, msg.sender, coinbasePaid, tx.gasprice - block.basefee);
     }
-}
\ No newline at end of file
+}
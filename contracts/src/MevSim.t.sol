@@ -4,6 +4,8 @@ import "forge-std/Test.sol";
 import "./MevSim.sol";
 
 contract MevSimTest is Test {
+    event Auctioned(uint256 indexed slot, uint256 newValue, address indexed bidder, uint256 coinbasePaid, uint256 tipPerGas);
+
     MevSim mevSim;
 
     function setUp() public {
@@ -16,5 +18,23 @@ contract MevSimTest is Test {
         mevSim.auction{ value: 1}(1, value, block.number);
         assertEq(mevSim.getSlot(1), value + 1);
     }
-}
 
+    function testLastWinner() public {
+        uint value = mevSim.getSlot(2);
+        vm.expectEmit(true, true, false, true);
+        emit Auctioned(2, value + 1, address(this), 1, tx.gasprice - block.basefee);
+        mevSim.auction{ value: 1}(2, value, block.number);
+
+        (address bidder, uint256 blockNumber) = mevSim.getLastWinner(2);
+        assertEq(bidder, address(this));
+        assertEq(blockNumber, block.number);
+    }
+
+    function testAuctionWithBurn() public {
+        uint value = mevSim.getSlot(3);
+        uint gasBefore = gasleft();
+        mevSim.auctionWithBurn{ value: 1}(3, value, block.number, 100000, new bytes(1000));
+        assertGt(gasBefore - gasleft(), 100000);
+        assertEq(mevSim.getSlot(3), value + 1);
+    }
+}
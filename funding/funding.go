@@ -0,0 +1,84 @@
+package funding
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"go-bundles-go/chain"
+	"go-bundles-go/wallet"
+	"math/big"
+)
+
+// Amounts returns amount needed to bring each address up to targetBalance and their sum
+func Amounts(ctx context.Context, client chain.TxBackend, addresses []common.Address, targetBalance *big.Int) ([]*big.Int, *big.Int, error) {
+	fundAmounts := make([]*big.Int, len(addresses))
+	totalFundAmount := big.NewInt(0)
+	for i, address := range addresses {
+		balance, err := client.BalanceAt(ctx, address, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		sendValue := new(big.Int).Sub(targetBalance, balance)
+		if sendValue.Cmp(big.NewInt(0)) < 0 {
+			sendValue = big.NewInt(0)
+		}
+		fundAmounts[i] = sendValue
+		totalFundAmount = new(big.Int).Add(totalFundAmount, sendValue)
+	}
+	return fundAmounts, totalFundAmount, nil
+}
+
+// Send sends amounts[i] to addresses[i] from master wallet, zero amounts are skipped.
+// Returns transactions that were sent.
+func Send(ctx context.Context, client chain.TxBackend, masterWallet wallet.TxSigner, addresses []common.Address, amounts []*big.Int) ([]*types.Transaction, error) {
+	if len(addresses) != len(amounts) {
+		return nil, fmt.Errorf("addresses and amounts must be the same length")
+	}
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := client.PendingNonceAt(ctx, masterWallet.Address())
+	if err != nil {
+		return nil, err
+	}
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	gasTipCap, err := client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var sentTxs []*types.Transaction
+	for i := 0; i < len(addresses); i++ {
+		if amounts[i].Cmp(big.NewInt(0)) == 0 {
+			continue
+		}
+		address := addresses[i]
+		tx := types.NewTx(&types.DynamicFeeTx{
+			ChainID:   chainID,
+			Nonce:     nonce,
+			GasFeeCap: gasPrice,
+			GasTipCap: gasTipCap,
+			Gas:       21000,
+			To:        &address,
+			Value:     amounts[i],
+		})
+		nonce++
+
+		signedTx, err := masterWallet.SignTx(tx, chainID)
+		if err != nil {
+			return sentTxs, err
+		}
+		fmt.Printf("Sending %s to %s, hash: %s\n", chain.WeiToUnit(amounts[i], 1e18).String(), address.Hex(), signedTx.Hash().Hex())
+		err = client.SendTransaction(ctx, signedTx)
+		if err != nil {
+			return sentTxs, err
+		}
+		sentTxs = append(sentTxs, signedTx)
+	}
+	return sentTxs, nil
+}
@@ -0,0 +1,277 @@
+package funding
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"go-bundles-go/internal/simchain"
+	"go-bundles-go/wallet"
+	"math/big"
+	"testing"
+)
+
+// fakeAgent is supervised agent with a fixed next bid cost
+type fakeAgent struct {
+	address     common.Address
+	nextBidCost *big.Int
+	paused      bool
+}
+
+func (a *fakeAgent) Address() common.Address { return a.address }
+func (a *fakeAgent) NextBidCost() *big.Int   { return a.nextBidCost }
+func (a *fakeAgent) Paused() bool            { return a.paused }
+func (a *fakeAgent) SetPaused(paused bool)   { a.paused = paused }
+
+func milliEther(milli int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(milli), big.NewInt(1e15))
+}
+
+// newSimChain returns simulated chain where master and addresses have given balances
+func newSimChain(t *testing.T, masterBalance *big.Int, balances ...*big.Int) (*simchain.Backend, wallet.TxSigner, []common.Address) {
+	t.Helper()
+	master, masterAddress := simchain.NewKey(t)
+	alloc := core.GenesisAlloc{masterAddress: {Balance: masterBalance}}
+	addresses := make([]common.Address, len(balances))
+	for i, balance := range balances {
+		_, addresses[i] = simchain.NewKey(t)
+		alloc[addresses[i]] = core.GenesisAccount{Balance: balance}
+	}
+	return simchain.New(t, alloc), wallet.NewKeySigner(master), addresses
+}
+
+func balanceOf(t *testing.T, backend *simchain.Backend, address common.Address) *big.Int {
+	t.Helper()
+	balance, err := backend.BalanceAt(context.Background(), address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return balance
+}
+
+func TestAmountsSplit(t *testing.T) {
+	backend, _, addresses := newSimChain(t, milliEther(100000), big.NewInt(0), milliEther(400), milliEther(1000), milliEther(2000))
+
+	amounts, total, err := Amounts(context.Background(), backend, addresses, milliEther(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*big.Int{milliEther(1000), milliEther(600), big.NewInt(0), big.NewInt(0)}
+	for i := range expected {
+		if amounts[i].Cmp(expected[i]) != 0 {
+			t.Errorf("amount %d is %s, expected %s", i, amounts[i], expected[i])
+		}
+	}
+	if total.Cmp(milliEther(1600)) != 0 {
+		t.Errorf("total is %s, expected %s", total, milliEther(1600))
+	}
+}
+
+func TestSendSkipsZeroAmounts(t *testing.T) {
+	backend, master, addresses := newSimChain(t, milliEther(100000), big.NewInt(0), milliEther(1000), milliEther(400))
+	ctx := context.Background()
+
+	amounts, _, err := Amounts(ctx, backend, addresses, milliEther(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sentTxs, err := Send(ctx, backend, master, addresses, amounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sentTxs) != 2 {
+		t.Fatalf("sent %d txs, expected 2", len(sentTxs))
+	}
+	for i, tx := range sentTxs {
+		if tx.Nonce() != uint64(i) {
+			t.Errorf("tx %d has nonce %d", i, tx.Nonce())
+		}
+	}
+	if *sentTxs[0].To() != addresses[0] || *sentTxs[1].To() != addresses[2] {
+		t.Errorf("txs sent to %s and %s", sentTxs[0].To().Hex(), sentTxs[1].To().Hex())
+	}
+
+	backend.Commit()
+	for i, address := range addresses {
+		if balance := balanceOf(t, backend, address); balance.Cmp(milliEther(1000)) != 0 {
+			t.Errorf("address %d balance is %s after funding", i, balance)
+		}
+	}
+
+	_, err = Send(ctx, backend, master, addresses[:1], nil)
+	if err == nil {
+		t.Error("expected error for mismatched amounts")
+	}
+}
+
+func TestSupervisorTopUpThreshold(t *testing.T) {
+	// below threshold, above threshold but below target, empty
+	backend, master, addresses := newSimChain(t, milliEther(100000), milliEther(100), milliEther(700), big.NewInt(0))
+	agents := make([]*fakeAgent, len(addresses))
+	supervised := make([]Agent, len(addresses))
+	for i, address := range addresses {
+		agents[i] = &fakeAgent{address: address, nextBidCost: milliEther(200)}
+		supervised[i] = agents[i]
+	}
+	supervisor := NewSupervisor(backend, master, supervised, milliEther(500), milliEther(1000))
+	ctx := context.Background()
+
+	err := supervisor.checkBalances(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(supervisor.pendingTopUps) != 2 {
+		t.Fatalf("%d top-ups pending, expected 2", len(supervisor.pendingTopUps))
+	}
+	if _, ok := supervisor.pendingTopUps[addresses[1]]; ok {
+		t.Error("wallet above threshold was topped up")
+	}
+	if !agents[0].paused || agents[1].paused || !agents[2].paused {
+		t.Errorf("paused %v %v %v, expected agents that can't afford next bid paused", agents[0].paused, agents[1].paused, agents[2].paused)
+	}
+
+	backend.Commit()
+	err = supervisor.checkBalances(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*big.Int{milliEther(1000), milliEther(700), milliEther(1000)}
+	for i, address := range addresses {
+		if balance := balanceOf(t, backend, address); balance.Cmp(expected[i]) != 0 {
+			t.Errorf("agent %d balance is %s, expected %s", i, balance, expected[i])
+		}
+		if agents[i].paused {
+			t.Errorf("agent %d still paused after top-up", i)
+		}
+	}
+	if len(supervisor.pendingTopUps) != 0 {
+		t.Errorf("%d top-ups pending after they were mined", len(supervisor.pendingTopUps))
+	}
+}
+
+func TestSupervisorSkipsPendingTopUp(t *testing.T) {
+	backend, master, addresses := newSimChain(t, milliEther(100000), milliEther(100))
+	supervisor := NewSupervisor(backend, master, []Agent{&fakeAgent{address: addresses[0]}}, milliEther(500), milliEther(1000))
+	ctx := context.Background()
+
+	err := supervisor.checkBalances(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	topUp, ok := supervisor.pendingTopUps[addresses[0]]
+	if !ok {
+		t.Fatal("wallet below threshold was not topped up")
+	}
+
+	// the top-up isn't mined yet so the wallet is still below threshold
+	err = supervisor.checkBalances(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce, err := backend.PendingNonceAt(ctx, master.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nonce != 1 {
+		t.Errorf("master sent %d txs, expected a single top-up", nonce)
+	}
+	if supervisor.pendingTopUps[addresses[0]].Hash() != topUp.Hash() {
+		t.Error("pending top-up was replaced")
+	}
+
+	backend.Commit()
+	if balance := balanceOf(t, backend, addresses[0]); balance.Cmp(milliEther(1000)) != 0 {
+		t.Errorf("balance is %s after top-up, expected %s", balance, milliEther(1000))
+	}
+}
+
+func TestSupervisorTopsUpUnaffordableAboveThreshold(t *testing.T) {
+	backend, master, addresses := newSimChain(t, milliEther(100000), milliEther(300), milliEther(300))
+	// next bid costs more than threshold, the second one more than target balance too
+	agents := []*fakeAgent{
+		{address: addresses[0], nextBidCost: milliEther(500)},
+		{address: addresses[1], nextBidCost: milliEther(2000)},
+	}
+	supervisor := NewSupervisor(backend, master, []Agent{agents[0], agents[1]}, milliEther(100), milliEther(1000))
+	ctx := context.Background()
+
+	err := supervisor.checkBalances(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !agents[0].paused || !agents[1].paused {
+		t.Fatal("agents that can't afford next bid weren't paused")
+	}
+	backend.Commit()
+	err = supervisor.checkBalances(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*big.Int{milliEther(1000), milliEther(2000)}
+	for i, address := range addresses {
+		if balance := balanceOf(t, backend, address); balance.Cmp(expected[i]) != 0 {
+			t.Errorf("agent %d balance is %s, expected %s", i, balance, expected[i])
+		}
+		if agents[i].paused {
+			t.Errorf("agent %d still paused after top-up", i)
+		}
+	}
+}
+
+func TestSupervisorResendsDroppedTopUp(t *testing.T) {
+	backend, master, addresses := newSimChain(t, milliEther(100000), milliEther(100))
+	supervisor := NewSupervisor(backend, master, []Agent{&fakeAgent{address: addresses[0]}}, milliEther(500), milliEther(1000))
+	ctx := context.Background()
+
+	err := supervisor.checkBalances(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := supervisor.pendingTopUps[addresses[0]]; !ok {
+		t.Fatal("wallet below threshold was not topped up")
+	}
+
+	// pending block is discarded, the top-up is no longer in the pool
+	backend.Rollback()
+	err = supervisor.checkBalances(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce, err := backend.PendingNonceAt(ctx, master.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nonce != 1 {
+		t.Fatalf("master has %d pending txs, expected dropped top-up to be sent again", nonce)
+	}
+
+	backend.Commit()
+	if balance := balanceOf(t, backend, addresses[0]); balance.Cmp(milliEther(1000)) != 0 {
+		t.Errorf("balance is %s after top-up, expected %s", balance, milliEther(1000))
+	}
+}
+
+func TestSupervisorFundsPausedAgentsFirst(t *testing.T) {
+	// master can cover only one of the top-ups
+	backend, master, addresses := newSimChain(t, milliEther(1100), milliEther(400), big.NewInt(0))
+	agents := []*fakeAgent{
+		{address: addresses[0], nextBidCost: milliEther(100)},
+		{address: addresses[1], nextBidCost: milliEther(100)},
+	}
+	supervisor := NewSupervisor(backend, master, []Agent{agents[0], agents[1]}, milliEther(500), milliEther(1000))
+
+	err := supervisor.checkBalances(context.Background())
+	if err == nil {
+		t.Error("expected error for top-ups master can't cover")
+	}
+	if _, ok := supervisor.pendingTopUps[addresses[1]]; !ok {
+		t.Error("paused agent was not topped up")
+	}
+	if _, ok := supervisor.pendingTopUps[addresses[0]]; ok {
+		t.Error("agent that can afford its next bid was topped up before paused one")
+	}
+
+	backend.Commit()
+	if balance := balanceOf(t, backend, addresses[1]); balance.Cmp(milliEther(1000)) != 0 {
+		t.Errorf("paused agent balance is %s, expected %s", balance, milliEther(1000))
+	}
+}
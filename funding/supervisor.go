@@ -0,0 +1,214 @@
+package funding
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"go-bundles-go/chain"
+	"go-bundles-go/wallet"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// Backend is node api used by Supervisor, implemented by *ethclient.Client
+type Backend interface {
+	chain.TxBackend
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	BlockNumber(ctx context.Context) (uint64, error)
+}
+
+// Agent is bidder whose wallet is kept funded by Supervisor
+type Agent interface {
+	Address() common.Address
+	// NextBidCost returns max amount of wei the next bid can spend or nil if it's unknown yet
+	NextBidCost() *big.Int
+	Paused() bool
+	SetPaused(paused bool)
+}
+
+// Supervisor keeps agent wallets funded during long runs.
+// Every block it tops up wallets that fell below threshold back to targetBalance
+// and pauses agents that can't afford their next bid until they are funded again, these are topped up
+// regardless of threshold and first when master wallet can't cover every top-up.
+// Top-ups that are dropped or replaced are sent again.
+type Supervisor struct {
+	client        Backend
+	masterWallet  wallet.TxSigner
+	agents        []Agent
+	threshold     *big.Int
+	targetBalance *big.Int
+
+	// top-ups that are sent but not yet mined, by agent address
+	pendingTopUps map[common.Address]*types.Transaction
+}
+
+func NewSupervisor(client Backend, masterWallet wallet.TxSigner, agents []Agent, threshold, targetBalance *big.Int) *Supervisor {
+	return &Supervisor{
+		client:        client,
+		masterWallet:  masterWallet,
+		agents:        agents,
+		threshold:     threshold,
+		targetBalance: targetBalance,
+		pendingTopUps: make(map[common.Address]*types.Transaction),
+	}
+}
+
+func (s *Supervisor) Run(ctx context.Context) error {
+	var lastBlockNumber uint64
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		blockNumber, err := s.client.BlockNumber(ctx)
+		if err != nil {
+			continue
+		}
+		if blockNumber == lastBlockNumber {
+			continue
+		}
+		lastBlockNumber = blockNumber
+
+		err = s.checkBalances(ctx)
+		if err != nil {
+			fmt.Println("funding supervisor error", err)
+		}
+	}
+}
+
+// forgetTopUps forgets top-ups that were mined and ones that were dropped or replaced, so the latter are sent again
+func (s *Supervisor) forgetTopUps(ctx context.Context) error {
+	if len(s.pendingTopUps) == 0 {
+		return nil
+	}
+	masterAddress := s.masterWallet.Address()
+	nonce, err := s.client.NonceAt(ctx, masterAddress, nil)
+	if err != nil {
+		return err
+	}
+	pendingNonce, err := s.client.PendingNonceAt(ctx, masterAddress)
+	if err != nil {
+		return err
+	}
+	for address, tx := range s.pendingTopUps {
+		receipt, err := s.client.TransactionReceipt(ctx, tx.Hash())
+		if err == nil && receipt != nil {
+			delete(s.pendingTopUps, address)
+			continue
+		}
+		// nonce was used by another tx or the tx is no longer in the pool
+		if nonce > tx.Nonce() || pendingNonce <= tx.Nonce() {
+			fmt.Println("top-up dropped or replaced", "agent", address.Hex(), "hash", tx.Hash().Hex())
+			delete(s.pendingTopUps, address)
+		}
+	}
+	return nil
+}
+
+func (s *Supervisor) checkBalances(ctx context.Context) error {
+	err := s.forgetTopUps(ctx)
+	if err != nil {
+		return err
+	}
+
+	type topUp struct {
+		address common.Address
+		amount  *big.Int
+		paused  bool
+	}
+	var topUps []topUp
+	for _, agent := range s.agents {
+		address := agent.Address()
+		balance, err := s.client.BalanceAt(ctx, address, nil)
+		if err != nil {
+			return err
+		}
+
+		nextBidCost := agent.NextBidCost()
+		canAfford := nextBidCost == nil || balance.Cmp(nextBidCost) >= 0
+		if canAfford && agent.Paused() {
+			fmt.Println("resuming agent", address.Hex(), "balance", chain.WeiToUnit(balance, 1e18))
+			agent.SetPaused(false)
+		} else if !canAfford && !agent.Paused() {
+			fmt.Println("pausing agent", address.Hex(), "balance", chain.WeiToUnit(balance, 1e18), "nextBidCost", chain.WeiToUnit(nextBidCost, 1e18))
+			agent.SetPaused(true)
+		}
+
+		// agents that can't afford the next bid are topped up even above threshold, they would stay paused otherwise
+		if canAfford && balance.Cmp(s.threshold) >= 0 {
+			continue
+		}
+		if _, ok := s.pendingTopUps[address]; ok {
+			continue
+		}
+		targetBalance := s.targetBalance
+		if !canAfford && nextBidCost.Cmp(targetBalance) > 0 {
+			targetBalance = nextBidCost
+		}
+		amount := new(big.Int).Sub(targetBalance, balance)
+		if amount.Sign() <= 0 {
+			continue
+		}
+		topUps = append(topUps, topUp{address: address, amount: amount, paused: !canAfford})
+	}
+	if len(topUps) == 0 {
+		return nil
+	}
+	// paused agents are funded first when master wallet can't cover every top-up
+	sort.SliceStable(topUps, func(i, j int) bool {
+		return topUps[i].paused && !topUps[j].paused
+	})
+
+	masterBalance, err := s.client.BalanceAt(ctx, s.masterWallet.Address(), nil)
+	if err != nil {
+		return err
+	}
+	gasPrice, err := s.client.SuggestGasPrice(ctx)
+	if err != nil {
+		return err
+	}
+	txFee := new(big.Int).Mul(gasPrice, big.NewInt(21000))
+
+	var (
+		topUpAddresses []common.Address
+		topUpAmounts   []*big.Int
+		totalCost      = big.NewInt(0)
+		skipped        []topUp
+	)
+	for _, topUp := range topUps {
+		cost := new(big.Int).Add(topUp.amount, txFee)
+		if new(big.Int).Add(totalCost, cost).Cmp(masterBalance) > 0 {
+			skipped = append(skipped, topUp)
+			continue
+		}
+		topUpAddresses = append(topUpAddresses, topUp.address)
+		topUpAmounts = append(topUpAmounts, topUp.amount)
+		totalCost.Add(totalCost, cost)
+	}
+
+	if len(topUpAddresses) > 0 {
+		sentTxs, err := Send(ctx, s.client, s.masterWallet, topUpAddresses, topUpAmounts)
+		for _, tx := range sentTxs {
+			s.pendingTopUps[*tx.To()] = tx
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(skipped) > 0 {
+		needed := big.NewInt(0)
+		for _, topUp := range skipped {
+			needed.Add(needed, topUp.amount)
+		}
+		return fmt.Errorf("master wallet balance insufficient for %d of %d top-ups, balance %s, %s more needed",
+			len(skipped), len(topUps), chain.WeiToUnit(masterBalance, 1e18).String(), chain.WeiToUnit(needed, 1e18).String())
+	}
+	return nil
+}
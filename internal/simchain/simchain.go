@@ -0,0 +1,75 @@
+// Package simchain is simulated chain shared by tests of packages that talk to the node
+package simchain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"testing"
+)
+
+// GasLimit is block gas limit of simulated chains
+const GasLimit = 30000000
+
+// Backend is simulated backend with the node api it lacks
+type Backend struct {
+	*backends.SimulatedBackend
+	// AutoMine commits a block after every sent transaction, so callers waiting until their txs are mined don't block
+	AutoMine bool
+}
+
+// New returns simulated chain with alloc in genesis, it's closed when the test ends
+func New(t testing.TB, alloc core.GenesisAlloc) *Backend {
+	t.Helper()
+	backend := backends.NewSimulatedBackend(alloc, GasLimit)
+	t.Cleanup(func() { backend.Close() })
+	return &Backend{SimulatedBackend: backend}
+}
+
+// NewKey generates key of a new account
+func NewKey(t testing.TB) (*ecdsa.PrivateKey, common.Address) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, crypto.PubkeyToAddress(key.PublicKey)
+}
+
+func (b *Backend) ChainID(ctx context.Context) (*big.Int, error) {
+	return b.Blockchain().Config().ChainID, nil
+}
+
+func (b *Backend) BlockNumber(ctx context.Context) (uint64, error) {
+	return b.Blockchain().CurrentBlock().NumberU64(), nil
+}
+
+// EstimateGas prices gas estimation calls at the pending base fee, simulated backend keeps base fee
+// of zero priced calls so MevSim tip calculation would underflow
+func (b *Backend) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	if call.GasPrice == nil && call.GasFeeCap == nil {
+		baseFee, err := b.SuggestGasPrice(ctx)
+		if err != nil {
+			return 0, err
+		}
+		call.GasPrice = baseFee
+	}
+	return b.SimulatedBackend.EstimateGas(ctx, call)
+}
+
+func (b *Backend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	err := b.SimulatedBackend.SendTransaction(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if b.AutoMine {
+		b.Commit()
+	}
+	return nil
+}
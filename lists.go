@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"go-bundles-go/agent"
+	"go-bundles-go/arrival"
+	"go-bundles-go/chain"
+	"go-bundles-go/valuation"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IndexRange returns count consecutive indices starting at start
+func IndexRange(start int, count int) ([]int, error) {
+	if start < 0 || count < 0 {
+		return nil, fmt.Errorf("invalid index range of %d wallets from %d", count, start)
+	}
+	indices := make([]int, count)
+	for i := range indices {
+		indices[i] = start + i
+	}
+	return indices, nil
+}
+
+// ParseIndexRanges parses ranges of wallet indices joined with "+", e.g. "1-4+8+10-11"
+func ParseIndexRanges(s string) ([]int, error) {
+	var result []int
+	seen := make(map[int]bool)
+	for _, r := range strings.Split(s, "+") {
+		from, to, isRange := strings.Cut(r, "-")
+		start, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, err
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(to)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if start < 0 || end < start {
+			return nil, fmt.Errorf("invalid index range %s", r)
+		}
+		for i := start; i <= end; i++ {
+			if seen[i] {
+				return nil, fmt.Errorf("index %d is used more than once", i)
+			}
+			seen[i] = true
+			result = append(result, i)
+		}
+	}
+	return result, nil
+}
+
+// ParseIndexGroups parses comma separated list of index ranges, e.g. "1-4+8,10-13".
+// An index can be in one group only, agents of different groups would share its wallet.
+func ParseIndexGroups(s string) ([][]int, error) {
+	var groups [][]int
+	group := make(map[int]int)
+	for i, r := range strings.Split(s, ",") {
+		indices, err := ParseIndexRanges(r)
+		if err != nil {
+			return nil, err
+		}
+		for _, index := range indices {
+			if g, ok := group[index]; ok {
+				return nil, fmt.Errorf("index %d is in groups %d and %d", index, g+1, i+1)
+			}
+			group[index] = i
+		}
+		groups = append(groups, indices)
+	}
+	return groups, nil
+}
+
+func ParseIntList(s string) ([]int, error) {
+	var result []int
+	for _, v := range strings.Split(s, ",") {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, i)
+	}
+	return result, nil
+}
+
+func ParseFloatList(s string) ([]float64, error) {
+	var result []float64
+	for _, v := range strings.Split(s, ",") {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, f)
+	}
+	return result, nil
+}
+
+func ParseDurationList(s string) ([]time.Duration, error) {
+	var result []time.Duration
+	for _, v := range strings.Split(s, ",") {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+	}
+	return result, nil
+}
+
+// ExpandList repeats single value list n times, other lists are returned as is
+func ExpandList[T any](list []T, n int) []T {
+	if len(list) != 1 {
+		return list
+	}
+	for len(list) < n {
+		list = append(list, list[0])
+	}
+	return list
+}
+
+func ParseTxTypeList(s string) ([]uint8, error) {
+	var result []uint8
+	for _, v := range strings.Split(s, ",") {
+		txType, err := chain.ParseTxType(v)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, txType)
+	}
+	return result, nil
+}
+
+func ParseAccessListModeList(s string) ([]string, error) {
+	var result []string
+	for _, v := range strings.Split(s, ",") {
+		mode, err := agent.ParseAccessListMode(v)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, mode)
+	}
+	return result, nil
+}
+
+func ParseArrivalModelList(s string) ([]string, error) {
+	var result []string
+	for _, v := range strings.Split(s, ",") {
+		model, err := arrival.ParseModel(v)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, model)
+	}
+	return result, nil
+}
+
+func ParseValuationModelList(s string) ([]string, error) {
+	var result []string
+	for _, v := range strings.Split(s, ",") {
+		model, err := valuation.ParseModel(v)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, model)
+	}
+	return result, nil
+}
+
+func ParseStrategyList(s string) ([]string, error) {
+	var result []string
+	for _, v := range strings.Split(s, ",") {
+		strategy, err := agent.ParseStrategy(v)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, strategy)
+	}
+	return result, nil
+}
@@ -3,27 +3,78 @@ package main
 import (
 	"context"
 	"crypto/ecdsa"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"go-bundles-go/agent"
+	"go-bundles-go/arrival"
+	"go-bundles-go/bench"
+	"go-bundles-go/chain"
+	"go-bundles-go/contracts/mevsim"
+	"go-bundles-go/funding"
+	"go-bundles-go/ratelimit"
+	"go-bundles-go/recording"
+	"go-bundles-go/relay"
+	"go-bundles-go/transport"
+	"go-bundles-go/valuation"
+	"go-bundles-go/wallet"
+	"golang.org/x/time/rate"
 	"math/big"
+	"math/rand"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 var (
-	rpc      = flag.String("rpc", "http://localhost:8545", "rpc url")
-	mnemonic = flag.String("mnemonic", "panic keen way shuffle post attract clever country juice point pulp february", "mnemonic")
+	rpc             = flag.String("rpc", "http://localhost:8545", "rpc url")
+	expectedChainID = flag.Uint64("chain-id", 0, "expected chain id, 0 accepts any chain")
+	mnemonic        = flag.String("mnemonic", "", "mnemonic of master and searcher wallets, defaults to MNEMONIC environment variable")
+	mnemonicFile    = flag.String("mnemonic-file", "", "file with mnemonic of master and searcher wallets, overrides MNEMONIC environment variable")
 
-	deployCommand = flag.NewFlagSet("deploy", flag.ExitOnError)
+	keystoreFile        = flag.String("keystore", "", "encrypted keystore file of master wallet, overrides mnemonic")
+	searcherKeystoreDir = flag.String("searcher-keystore", "", "directory with encrypted keystore files of searcher wallets, overrides mnemonic")
+	searcherKeyFile     = flag.String("searcher-keys", "", "file with hex encoded private keys of searcher wallets, one per line, overrides mnemonic")
+	passwordFile        = flag.String("password-file", "", "file with keystore password")
+	clefUrl             = flag.String("clef", "", "clef endpoint, account 0 is used as master wallet and the rest as searcher wallets, overrides other key options")
+
+	hdPath        = flag.String("hd-path", wallet.DefaultPath, "base derivation path of mnemonic wallets")
+	hdMasterIndex = flag.Int("hd-master-index", 0, "derivation index of master wallet")
+	hdStartIndex  = flag.Int("hd-start", 1, "derivation index of the first searcher wallet")
+
+	httpMaxIdle     = flag.Int("http-max-idle", 512, "idle keep-alive connections per host of http transport shared by all agents, should cover requests in flight")
+	httpIdleTimeout = flag.Duration("http-idle-timeout", 90*time.Second, "time idle connections are kept open")
+	httpHTTP2       = flag.Bool("http2", true, "negotiate http/2 with https endpoints")
+
+	rpcTimeout     = flag.Duration("rpc-timeout", 30*time.Second, "timeout of http rpc requests, 0 disables")
+	rpcHeaders     = flag.String("rpc-headers", "", "extra headers of rpc requests, comma separated list of name:value")
+	rpcBearer      = flag.String("rpc-bearer", "", "bearer token of rpc requests")
+	rpcJWTSecret   = flag.String("rpc-jwt-secret", "", "file with hex jwt secret, every rpc request gets fresh HS256 bearer token, overrides rpc-bearer")
+	rpcGzip        = flag.Bool("rpc-gzip", false, "gzip rpc request bodies")
+	relayHeaders   = flag.String("relay-headers", "", "extra headers of relay requests, comma separated list of name:value")
+	relayBearer    = flag.String("relay-bearer", "", "bearer token of relay requests")
+	relayJWTSecret = flag.String("relay-jwt-secret", "", "file with hex jwt secret, every relay request gets fresh HS256 bearer token, overrides relay-bearer")
+	relayGzip      = flag.Bool("relay-gzip", false, "gzip relay request bodies")
+
+	// shared by http clients of all endpoints, created after flags are parsed
+	httpTransport *http.Transport
+
+	deployCommand       = flag.NewFlagSet("deploy", flag.ExitOnError)
+	deployGasLimit      = deployCommand.Uint64("gas-limit", 0, "gas limit of deploy tx, 0 estimates gas")
+	deployGasMultiplier = deployCommand.Float64("gas-mult", 1.2, "safety multiplier applied to estimated gas")
+	deployCreate2       = deployCommand.Bool("create2", true, "deploy through create2 factory to the same address on every chain, skip if already deployed")
 
 	fundCommand = flag.NewFlagSet("fund", flag.ExitOnError)
 	fundCheck   = fundCommand.Bool("check", false, "only check balances")
 	fundAmount  = fundCommand.Int64("amount", 1000000000000000000, "target balance of searcher wallets")
 	fundCount   = fundCommand.Int("count", 10, "number of accounts to fund")
+	fundIndices = fundCommand.String("indices", "", "derivation indices of accounts to fund joined with +, e.g. 1-10+20-29, overrides count and hd-start")
 
 	runCommand              = flag.NewFlagSet("run", flag.ExitOnError)
 	runFlashbotsRpc         = runCommand.String("fb-rpc", "http://localhost:8545", "flashbots rpc endpoint")
@@ -31,8 +82,97 @@ var (
 	runCount                = runCommand.String("count", "1,1", "number of agents per slot, comma separated list")
 	runStartEffGasPrices    = runCommand.String("start-gp", "5,6", "starting effective gas price(gwei), comma separated list")
 	runIncrementEffGasPrice = runCommand.String("inc-gp", "1,2", "increment effective gas price(gwei), comma separated list")
-	runBidRate              = runCommand.Uint64("rate", 10, "bids per second")
-	runMevSimAddr           = runCommand.String("mevsim-addr", "0xafcb5f59eca70854780c04f4fdb04198b969b7ea", "mev sim address")
+	runBidRate              = runCommand.Uint64("rate", 10, "bids per second of every agent, 0 leaves rate to -global-rate and -relay-rate")
+	runArrival              = runCommand.String("arrival", "regular", "arrival model of bids: regular, poisson, onoff, pareto or trace, comma separated list or single value for all slots")
+	runArrivalSeed          = runCommand.Int64("arrival-seed", 0, "seed of random arrival models, 0 uses current time")
+	runOnMean               = runCommand.Duration("on-mean", 500*time.Millisecond, "mean ON period of onoff arrivals")
+	runOffMean              = runCommand.Duration("off-mean", 2*time.Second, "mean OFF period of onoff arrivals")
+	runParetoAlpha          = runCommand.Float64("pareto-alpha", 1.5, "shape of pareto arrivals, > 1, lower gives heavier tail")
+	runTraceFile            = runCommand.String("trace-file", "", "csv with relay arrival timestamps in the first column (unix seconds or RFC3339) for trace arrivals")
+	runGlobalRate           = runCommand.Float64("global-rate", 0, "bids per second of all agents together, 0 disables")
+	runGlobalBurst          = runCommand.Int("global-burst", 1, "burst of -global-rate limiter")
+	runRelayRate            = runCommand.Float64("relay-rate", 0, "requests per second sent to relay by all agents including retries and simulations, 0 disables")
+	runRelayBurst           = runCommand.Int("relay-burst", 1, "burst of -relay-rate limiter")
+	runSchedule             = runCommand.String("schedule", "fair", "sharing of -global-rate and -relay-rate between slots: fair(in turns) or priority(by -priority)")
+	runPriority             = runCommand.String("priority", "0", "scheduling priority of slot agents, higher is served first, comma separated list or single value for all slots")
+	runStrategy             = runCommand.String("strategy", "linear", "bid strategy: linear(start-gp raised by inc-gp every bid), bandit(learns start and increment across blocks) or margin(outbids observed rivals starting at start-gp), comma separated list or single value for all slots")
+	runBanditStart          = runCommand.String("bandit-start", "1,2,5,10", "starting effective gas prices(gwei) bandit chooses from, comma separated list")
+	runBanditInc            = runCommand.String("bandit-inc", "0,0.1,1", "increments(gwei) bandit chooses from, comma separated list, every start and increment pair is an arm")
+	runBanditValue          = runCommand.Float64("bandit-value", 20, "tip per gas(gwei) worth paying to win the slot, bandit reward of agents without valuation")
+	runBanditExploration    = runCommand.Float64("bandit-exploration", 1, "weight of exploration of bandit arms")
+	runBanditState          = runCommand.String("bandit-state", "", "directory learned bandit state is loaded from and saved to, one file per agent, empty disables")
+	runMargin               = runCommand.Float64("margin", 1, "margin(gwei) of margin strategy above the previous winning tip or the best rival bid seen in the mempool")
+	runMarginPercent        = runCommand.Float64("margin-pct", 0, "margin of margin strategy in percent of the observed bid, added to -margin")
+	runMempoolWs            = runCommand.String("mempool-ws", "", "websocket rpc of node whose mempool is watched for rival bids of margin strategy, empty disables")
+	runValuation            = runCommand.String("valuation", "none", "private valuation of winning the slot drawn every block: none, fixed, uniform, normal or lognormal, comma separated list or single value for all slots")
+	runValuationMean        = runCommand.String("valuation-mean", "0.01", "mean valuation(eth), comma separated list or single value for all slots")
+	runValuationSpread      = runCommand.String("valuation-spread", "0", "valuation spread(eth): half width of uniform, standard deviation of normal and lognormal, comma separated list or single value for all slots")
+	runValuationSeed        = runCommand.Int64("valuation-seed", 0, "seed of valuations, 0 uses current time")
+	runSlotDuration         = runCommand.Duration("slot-duration", agent.DefaultSlotDuration, "time between blocks, bid timing is measured from the parent block timestamp")
+	runBidStart             = runCommand.String("bid-start", "0s", "delay of the first bid after the parent block, comma separated list or single value for all slots")
+	runBidDeadline          = runCommand.String("bid-deadline", "0s", "stop bidding this long before the expected next block, comma separated list or single value for all slots")
+	runBidLast              = runCommand.String("bid-last", "0s", "bid only during this long window before the deadline, 0 disables, comma separated list or single value for all slots")
+	runTxTypes              = runCommand.String("tx-type", "dynamic-fee", "transaction type: legacy, access-list or dynamic-fee, comma separated list or single value for all slots")
+	runAccessList           = runCommand.String("access-list", "none", "access list of auction txs: none, static or rpc(eth_createAccessList), comma separated list or single value for all slots")
+	runAccessListGas        = runCommand.Bool("access-list-gas", false, "log gas used by auction txs with and without access list, two extra eth_estimateGas calls per agent every block")
+	runGasLimit             = runCommand.Uint64("gas-limit", 0, "fixed gas limit of bids, 0 estimates gas every block")
+	runGasEstimate          = runCommand.String("gas-estimate", "estimate", "gas estimation method: estimate(eth_estimateGas) or call-bundle(eth_callBundle on fb-rpc)")
+	runGasMultiplier        = runCommand.Float64("gas-mult", 1.2, "safety multiplier applied to estimated gas")
+	runBurnGas              = runCommand.String("burn-gas", "0", "extra gas burned by every bid, comma separated list or single value for all slots")
+	runPadding              = runCommand.String("padding", "0", "extra calldata bytes of every bid, comma separated list or single value for all slots")
+	runMevSimAddr           = runCommand.String("mevsim-addr", mevsim.Address().Hex(), "mev sim address, defaults to create2 address used by deploy")
+	runTopUpThreshold       = runCommand.Int64("topup-threshold", 0, "top up searcher wallets from master wallet when balance falls below this value(wei), 0 disables")
+	runTopUpAmount          = runCommand.Int64("topup-amount", 1000000000000000000, "target balance of topped up searcher wallets(wei)")
+	runSkipPreflight        = runCommand.Bool("skip-preflight", false, "start agents even if pre-flight checks fail")
+	runRelayTimeout         = runCommand.Duration("relay-timeout", relay.DefaultTimeout, "timeout of relay requests")
+	runBreakerThreshold     = runCommand.Int("breaker-threshold", 5, "consecutive transport, 429 or 5xx relay failures that open relay circuit breaker")
+	runBreakerCooldown      = runCommand.Duration("breaker-cooldown", time.Second, "time relay circuit breaker stays open, doubles while relay keeps failing")
+	runBreakerMaxCooldown   = runCommand.Duration("breaker-max-cooldown", 30*time.Second, "max time relay circuit breaker stays open")
+	runBatchState           = runCommand.Bool("batch-state", true, "read slot values, nonces and fee data of all agents in one json-rpc batch per block instead of separate calls of every agent")
+	runStatePoll            = runCommand.Duration("state-poll", 100*time.Millisecond, "interval the head is polled at by -batch-state")
+	runStatsInterval        = runCommand.Duration("stats-interval", 10*time.Second, "interval of relay and node error stats output, 0 disables")
+	runDryRun               = runCommand.Bool("dry-run", false, "build and sign bids but write eth_sendBundle requests to -dry-run-out instead of sending them to fb-rpc")
+	runDryRunOut            = runCommand.String("dry-run-out", "-", "file dry run requests are written to as json lines, - for stdout with logs on stderr")
+	runHDIndices            = runCommand.String("hd-indices", "", "derivation indices of agents per slot joined with +, comma separated list, e.g. 1-4+8,10-13, overrides count and hd-start")
+
+	recordCommand  = flag.NewFlagSet("record", flag.ExitOnError)
+	recordListen   = recordCommand.String("listen", "localhost:18545", "address of relay proxy, point -fb-rpc of run at it")
+	recordUpstream = recordCommand.String("upstream", "", "relay requests are forwarded to, empty accepts bundles without sending them")
+	recordOut      = recordCommand.String("out", "recording.jsonl", "file eth_sendBundle requests are appended to as json lines")
+	recordTimeout  = recordCommand.Duration("timeout", relay.DefaultTimeout, "timeout of upstream requests")
+
+	replayCommand     = flag.NewFlagSet("replay", flag.ExitOnError)
+	replayIn          = replayCommand.String("in", "recording.jsonl", "recording made by record")
+	replayTarget      = replayCommand.String("target", "http://localhost:8545", "relay or builder endpoint bundles are replayed to")
+	replaySpeed       = replayCommand.Float64("speed", 1, "replay speed: 1 keeps original timing, 2 is twice as fast, 0 sends as fast as possible")
+	replayConcurrency = replayCommand.Int("concurrency", 16, "requests in flight")
+	replayRemap       = replayCommand.Bool("remap", true, "shift target blocks so the first recorded target is the block after the current head of -rpc")
+	replayResign      = replayCommand.Bool("resign", false, "re-sign txs of loaded searcher wallets with current nonces, slot values and remapped target blocks")
+	replayCount       = replayCommand.Int("count", 0, "number of searcher wallets loaded for -resign and relay signatures, starting at hd-start")
+	replayHDIndices   = replayCommand.String("hd-indices", "", "derivation indices of searcher wallets joined with +, e.g. 1-10+20, overrides count and hd-start")
+	replayMevSimAddr  = replayCommand.String("mevsim-addr", mevsim.Address().Hex(), "mev sim address, defaults to create2 address used by deploy")
+	replayTimeout     = replayCommand.Duration("relay-timeout", relay.DefaultTimeout, "timeout of relay requests")
+
+	benchCommand          = flag.NewFlagSet("bench", flag.ExitOnError)
+	benchTarget           = benchCommand.String("target", "http://localhost:8545", "relay endpoint bundles are sent to")
+	benchCount            = benchCommand.Int("count", 1, "number of searcher wallets signing bundles, starting at hd-start")
+	benchHDIndices        = benchCommand.String("hd-indices", "", "derivation indices of searcher wallets joined with +, e.g. 1-10+20, overrides count and hd-start")
+	benchBundles          = benchCommand.Int("bundles", 1000, "number of bundles signed before the benchmark and sent in turns")
+	benchSlot             = benchCommand.Int64("slot", 0, "slot bundles bid on")
+	benchTip              = benchCommand.Float64("tip", 1, "effective gas price(gwei) of bundles")
+	benchGasLimit         = benchCommand.Uint64("gas-limit", 100000, "gas limit of bundle txs")
+	benchStartRate        = benchCommand.Float64("start-rate", 10, "bundles per second of the first step")
+	benchStartConcurrency = benchCommand.Int("start-concurrency", 1, "requests in flight of the first step")
+	benchFactor           = benchCommand.Float64("factor", 2, "rate and concurrency multiplier of every next step")
+	benchMaxRate          = benchCommand.Float64("max-rate", 0, "stop before steps above this rate, 0 steps up until the SLO is violated")
+	benchMaxConcurrency   = benchCommand.Int("max-concurrency", 256, "max requests in flight")
+	benchStepDuration     = benchCommand.Duration("step", 10*time.Second, "duration of every step")
+	benchRefine           = benchCommand.Int("refine", 3, "bisection steps between the last step within SLO and the first violating it")
+	benchSLOP99           = benchCommand.Duration("slo-p99", 250*time.Millisecond, "p99 acceptance latency target, 0 disables")
+	benchSLOErrorRate     = benchCommand.Float64("slo-errors", 0.01, "error rate target")
+	benchOut              = benchCommand.String("out", "bench.json", "file json result is written to, - for stdout (logs then go to stderr)")
+	benchMevSimAddr       = benchCommand.String("mevsim-addr", mevsim.Address().Hex(), "mev sim address, defaults to create2 address used by deploy")
+	benchTimeout          = benchCommand.Duration("relay-timeout", relay.DefaultTimeout, "timeout of relay requests")
 )
 
 func ExecuteDeployCmd(args []string) error {
@@ -41,11 +181,25 @@ func ExecuteDeployCmd(args []string) error {
 		deployCommand.Usage()
 		return err
 	}
-	privateKey, _, err := DeriveWallets(*mnemonic, 1)
+	deployer, _, err := LoadWallets(nil)
+	if err != nil {
+		return err
+	}
+	node, err := dialNode()
 	if err != nil {
 		return err
 	}
-	_, err = DeployBidContract(*rpc, MevSimBytecode, privateKey)
+	defer node.Close()
+	client := node.Client
+	chainID, err := chain.VerifyChainID(context.Background(), client, *expectedChainID)
+	if err != nil {
+		return err
+	}
+	if *deployCreate2 {
+		_, err = mevsim.DeployCreate2(context.Background(), client, chainID, deployer, *deployGasLimit, *deployGasMultiplier)
+		return err
+	}
+	_, err = mevsim.Deploy(context.Background(), client, chainID, deployer, *deployGasLimit, *deployGasMultiplier)
 	return err
 }
 
@@ -55,12 +209,21 @@ func ExecuteRunCmd(args []string) error {
 		runCommand.Usage()
 		return err
 	}
+	var dryRunStdout *os.File
+	if *runDryRun && *runDryRunOut == "-" {
+		// requests are the only output on stdout, so it stays valid json lines
+		dryRunStdout = reserveStdout()
+	}
 	var (
 		slots             []*big.Int
 		count             []int
 		startEffGasPrices []*big.Int
 		incEffGasPrices   []*big.Int
-		privateKeys       [][]*ecdsa.PrivateKey
+		txTypes           []uint8
+		accessListModes   []string
+		burnGas           []int
+		padding           []int
+		searchers         [][]wallet.TxSigner
 	)
 	if slotsInt, err := ParseIntList(*runSlots); err == nil {
 		for _, slot := range slotsInt {
@@ -69,9 +232,25 @@ func ExecuteRunCmd(args []string) error {
 	} else {
 		return err
 	}
-	count, err = ParseIntList(*runCount)
-	if err != nil {
-		return err
+	var indices [][]int
+	if *runHDIndices != "" {
+		indices, err = ParseIndexGroups(*runHDIndices)
+		if err != nil {
+			return err
+		}
+		for _, groupIndices := range indices {
+			count = append(count, len(groupIndices))
+		}
+	} else {
+		count, err = ParseIntList(*runCount)
+		if err != nil {
+			return err
+		}
+		for _, c := range count {
+			if c < 0 {
+				return fmt.Errorf("count must be >= 0, got %d", c)
+			}
+		}
 	}
 	if startEffGasPricesFloat, err := ParseFloatList(*runStartEffGasPrices); err == nil {
 		for _, startEffGasPrice := range startEffGasPricesFloat {
@@ -89,44 +268,470 @@ func ExecuteRunCmd(args []string) error {
 	} else {
 		return err
 	}
-	if len(slots) != len(count) || len(slots) != len(startEffGasPrices) || len(slots) != len(incEffGasPrices) {
-		return fmt.Errorf("slots, count, startEffGasPrices, incEffGasPrices must be the same length")
+	txTypes, err = ParseTxTypeList(*runTxTypes)
+	if err != nil {
+		return err
+	}
+	txTypes = ExpandList(txTypes, len(slots))
+	accessListModes, err = ParseAccessListModeList(*runAccessList)
+	if err != nil {
+		return err
+	}
+	accessListModes = ExpandList(accessListModes, len(slots))
+	burnGas, err = ParseIntList(*runBurnGas)
+	if err != nil {
+		return err
+	}
+	burnGas = ExpandList(burnGas, len(slots))
+	padding, err = ParseIntList(*runPadding)
+	if err != nil {
+		return err
+	}
+	padding = ExpandList(padding, len(slots))
+	if len(slots) != len(count) || len(slots) != len(startEffGasPrices) || len(slots) != len(incEffGasPrices) ||
+		len(slots) != len(txTypes) || len(slots) != len(accessListModes) || len(slots) != len(burnGas) || len(slots) != len(padding) {
+		return fmt.Errorf("slots, count, startEffGasPrices, incEffGasPrices, txTypes, accessListModes, burnGas, padding must be the same length")
+	}
+	gasEstimateMode, err := agent.ParseGasEstimateMode(*runGasEstimate)
+	if err != nil {
+		return err
+	}
+	if *runDryRun && gasEstimateMode == agent.GasEstimateCallBundle && *runGasLimit == 0 {
+		return fmt.Errorf("dry run doesn't contact the relay, use -gas-estimate estimate or -gas-limit")
+	}
+	arrivalModels, err := ParseArrivalModelList(*runArrival)
+	if err != nil {
+		return err
+	}
+	arrivalModels = ExpandList(arrivalModels, len(slots))
+	if len(arrivalModels) != len(slots) {
+		return fmt.Errorf("arrival must be the same length as slots")
+	}
+	arrivalOpts := arrival.Options{OnMean: *runOnMean, OffMean: *runOffMean, ParetoAlpha: *runParetoAlpha}
+	for i, model := range arrivalModels {
+		switch model {
+		case arrival.ModelRegular:
+		case arrival.ModelTrace:
+			// all agents with trace arrivals share the trace
+			arrivalOpts.TraceAgents += count[i]
+		default:
+			if *runBidRate == 0 {
+				return fmt.Errorf("%s arrivals need rate > 0, slot %s", model, slots[i].String())
+			}
+		}
+	}
+	if arrivalOpts.TraceAgents > 0 {
+		if *runTraceFile == "" {
+			return fmt.Errorf("trace arrivals need trace-file")
+		}
+		arrivalOpts.Trace, err = arrival.LoadTrace(*runTraceFile)
+		if err != nil {
+			return err
+		}
+	}
+	arrivalSeed := *runArrivalSeed
+	if arrivalSeed == 0 {
+		arrivalSeed = time.Now().UnixNano()
+	}
+	scheduleMode, err := ratelimit.ParseMode(*runSchedule)
+	if err != nil {
+		return err
+	}
+	priorities, err := ParseIntList(*runPriority)
+	if err != nil {
+		return err
+	}
+	priorities = ExpandList(priorities, len(slots))
+	if len(priorities) != len(slots) {
+		return fmt.Errorf("priority must be the same length as slots")
+	}
+	valuationModels, err := ParseValuationModelList(*runValuation)
+	if err != nil {
+		return err
+	}
+	valuationModels = ExpandList(valuationModels, len(slots))
+	valuationMeans, err := ParseFloatList(*runValuationMean)
+	if err != nil {
+		return err
+	}
+	valuationMeans = ExpandList(valuationMeans, len(slots))
+	valuationSpreads, err := ParseFloatList(*runValuationSpread)
+	if err != nil {
+		return err
+	}
+	valuationSpreads = ExpandList(valuationSpreads, len(slots))
+	if len(valuationModels) != len(slots) || len(valuationMeans) != len(slots) || len(valuationSpreads) != len(slots) {
+		return fmt.Errorf("valuation, valuation-mean and valuation-spread must be the same length as slots")
+	}
+	var valuations *valuation.Ledger
+	for i, model := range valuationModels {
+		if model == valuation.ModelNone {
+			continue
+		}
+		// checks parameters before agents are created
+		_, err = valuation.New(model, valuationMeans[i]*1e18, valuationSpreads[i]*1e18, nil)
+		if err != nil {
+			return fmt.Errorf("slot %s: %w", slots[i].String(), err)
+		}
+		// one ledger is shared by agents of all slots with valuations
+		if valuations == nil {
+			valuations = valuation.NewLedger()
+		}
+	}
+	valuationSeed := *runValuationSeed
+	if valuationSeed == 0 {
+		valuationSeed = time.Now().UnixNano()
+	}
+	strategies, err := ParseStrategyList(*runStrategy)
+	if err != nil {
+		return err
+	}
+	strategies = ExpandList(strategies, len(slots))
+	if len(strategies) != len(slots) {
+		return fmt.Errorf("strategy must be the same length as slots")
+	}
+	banditStarts, err := ParseFloatList(*runBanditStart)
+	if err != nil {
+		return err
+	}
+	banditIncs, err := ParseFloatList(*runBanditInc)
+	if err != nil {
+		return err
+	}
+	var banditArms []agent.BanditArm
+	for _, start := range banditStarts {
+		for _, inc := range banditIncs {
+			banditArms = append(banditArms, agent.BanditArm{Start: big.NewInt(int64(start * 1e9)), Increment: big.NewInt(int64(inc * 1e9))})
+		}
+	}
+	var observations *agent.Observations
+	for _, strategy := range strategies {
+		if strategy == agent.StrategyMargin {
+			observations = agent.NewObservations()
+		}
+	}
+	if *runBanditState != "" {
+		err = os.MkdirAll(*runBanditState, 0o700)
+		if err != nil {
+			return err
+		}
+	}
+	if *runSlotDuration <= 0 {
+		return fmt.Errorf("slot-duration must be > 0")
+	}
+	bidStarts, err := ParseDurationList(*runBidStart)
+	if err != nil {
+		return err
+	}
+	bidStarts = ExpandList(bidStarts, len(slots))
+	bidDeadlines, err := ParseDurationList(*runBidDeadline)
+	if err != nil {
+		return err
+	}
+	bidDeadlines = ExpandList(bidDeadlines, len(slots))
+	bidLasts, err := ParseDurationList(*runBidLast)
+	if err != nil {
+		return err
+	}
+	bidLasts = ExpandList(bidLasts, len(slots))
+	if len(bidStarts) != len(slots) || len(bidDeadlines) != len(slots) || len(bidLasts) != len(slots) {
+		return fmt.Errorf("bid-start, bid-deadline and bid-last must be the same length as slots")
+	}
+	bidWindows := make([]agent.BidWindow, len(slots))
+	for i := range slots {
+		if bidStarts[i] < 0 || bidDeadlines[i] < 0 || bidLasts[i] < 0 {
+			return fmt.Errorf("bid-start, bid-deadline and bid-last must be >= 0, slot %s", slots[i].String())
+		}
+		bidWindows[i] = agent.BidWindow{StartOffset: bidStarts[i], Deadline: bidDeadlines[i], Last: bidLasts[i]}
+		if from, to := bidWindows[i].Bounds(*runSlotDuration); from >= to {
+			return fmt.Errorf("bid window of slot %s is empty", slots[i].String())
+		}
+	}
+	var (
+		sharedLimiters []*rate.Limiter
+		relayLimiter   *rate.Limiter
+	)
+	if *runGlobalRate > 0 {
+		sharedLimiters = append(sharedLimiters, rate.NewLimiter(rate.Limit(*runGlobalRate), *runGlobalBurst))
+	}
+	if *runRelayRate > 0 {
+		// relay client takes a token for every request on the wire, scheduler only paces and orders bids at the same rate
+		relayLimiter = rate.NewLimiter(rate.Limit(*runRelayRate), *runRelayBurst)
+		sharedLimiters = append(sharedLimiters, rate.NewLimiter(rate.Limit(*runRelayRate), *runRelayBurst))
+	}
+	var scheduler *ratelimit.Scheduler
+	if len(sharedLimiters) > 0 {
+		scheduler = ratelimit.NewScheduler(scheduleMode, priorities, sharedLimiters...)
+	} else if *runBidRate == 0 {
+		return fmt.Errorf("rate 0 requires global-rate or relay-rate")
+	}
+	if *runBreakerThreshold < 1 {
+		return fmt.Errorf("breaker-threshold must be >= 1")
+	}
+	for i := range slots {
+		if txTypes[i] == types.LegacyTxType && accessListModes[i] != agent.AccessListNone {
+			return fmt.Errorf("legacy transactions can't have access list, slot %s", slots[i].String())
+		}
+		if burnGas[i] < 0 || padding[i] < 0 {
+			return fmt.Errorf("burn gas and padding must be >= 0, slot %s", slots[i].String())
+		}
+	}
+
+	node, err := dialNode()
+	if err != nil {
+		return err
+	}
+	defer node.Close()
+	client := node.Client
+	chainID, err := chain.VerifyChainID(context.Background(), client, *expectedChainID)
+	if err != nil {
+		return err
 	}
 
 	totalCount := 0
 	for _, c := range count {
 		totalCount += c
 	}
-	_, pkeys, err := DeriveWallets(*mnemonic, totalCount)
-	for _, c := range count {
-		var keys []*ecdsa.PrivateKey
-		for i := 0; i < c; i++ {
-			keys = append(keys, pkeys[0])
-			pkeys = pkeys[1:]
+	var allIndices []int
+	if indices != nil {
+		for _, groupIndices := range indices {
+			allIndices = append(allIndices, groupIndices...)
+		}
+	} else {
+		allIndices, err = IndexRange(*hdStartIndex, totalCount)
+		if err != nil {
+			return err
 		}
-		privateKeys = append(privateKeys, keys)
+	}
+	masterWallet, signers, err := LoadWallets(allIndices)
+	if err != nil {
+		return err
+	}
+	for _, c := range count {
+		searchers = append(searchers, signers[:c])
+		signers = signers[c:]
 	}
 
-	doneChan := make(chan struct{}, totalCount)
-	mevSimAddr := common.HexToAddress(*runMevSimAddr)
+	var (
+		agents      []*agent.BundleAgent
+		traceAgents int
+	)
 	for i := 0; i < len(slots); i++ {
-		for _, key := range privateKeys[i] {
-			agent := BundleAgent{
-				slot:                 slots[i],
-				startingEffGasPrice:  startEffGasPrices[i],
-				incrementEffGasPrice: incEffGasPrices[i],
-				bidRate:              *runBidRate,
-				pk:                   key,
+		for _, signer := range searchers[i] {
+			relayKey, err := wallet.RelayKey(signer)
+			if err != nil {
+				return err
 			}
-
-			go func() {
-				err := agent.RunBundleAgent(*rpc, *runFlashbotsRpc, mevSimAddr)
+			var arrivalProcess arrival.Process
+			if arrivalModels[i] != arrival.ModelRegular {
+				opts := arrivalOpts
+				opts.TraceIndex = traceAgents
+				arrivalProcess, err = arrival.New(arrivalModels[i], float64(*runBidRate), opts, rand.New(rand.NewSource(arrivalSeed+int64(len(agents)))))
 				if err != nil {
-					fmt.Printf("error running agent: %v", err)
+					return err
 				}
-				doneChan <- struct{}{}
-			}()
+				if arrivalModels[i] == arrival.ModelTrace {
+					traceAgents++
+				}
+			}
+			valuationDistribution, err := valuation.New(valuationModels[i], valuationMeans[i]*1e18, valuationSpreads[i]*1e18, rand.New(rand.NewSource(valuationSeed+int64(len(agents)))))
+			if err != nil {
+				return err
+			}
+			var strategy agent.Strategy
+			if strategies[i] == agent.StrategyBandit {
+				statePath := ""
+				if *runBanditState != "" {
+					statePath = filepath.Join(*runBanditState, fmt.Sprintf("bandit-%s-%s.json", signer.Address().Hex(), slots[i].String()))
+				}
+				strategy, err = agent.NewBanditStrategy(banditArms, big.NewInt(int64(*runBanditValue*1e9)), *runBanditExploration, statePath)
+				if err != nil {
+					return err
+				}
+			} else if strategies[i] == agent.StrategyMargin {
+				strategy = agent.MarginStrategy{Start: startEffGasPrices[i], Margin: big.NewInt(int64(*runMargin * 1e9)), MarginPercent: *runMarginPercent}
+			}
+			agents = append(agents, agent.New(agent.Config{
+				Slot:                 slots[i],
+				StartingEffGasPrice:  startEffGasPrices[i],
+				IncrementEffGasPrice: incEffGasPrices[i],
+				Strategy:             strategy,
+				Valuation:            valuationDistribution,
+				Valuations:           valuations,
+				Observations:         observations,
+				BidRate:              *runBidRate,
+				Arrival:              arrivalProcess,
+				TxType:               txTypes[i],
+				AccessListMode:       accessListModes[i],
+				LogAccessListGas:     *runAccessListGas,
+				SlotDuration:         *runSlotDuration,
+				Window:               bidWindows[i],
+				BurnGas:              uint64(burnGas[i]),
+				PaddingBytes:         padding[i],
+				GasLimit:             *runGasLimit,
+				GasEstimateMode:      gasEstimateMode,
+				GasMultiplier:        *runGasMultiplier,
+				ChainID:              chainID,
+				Signer:               signer,
+				RelayKey:             relayKey,
+				Scheduler:            scheduler,
+				Group:                i,
+			}))
+		}
+	}
+
+	mevSimAddr := common.HexToAddress(*runMevSimAddr)
+	relayUrl := *runFlashbotsRpc
+	if *runDryRun {
+		// relay is not used
+		relayUrl = ""
+	}
+	// relay is probed with the client agents send with, so its headers and authentication are checked too
+	relayHTTP, err := relayHTTPClient(*runRelayTimeout)
+	if err != nil {
+		return err
+	}
+	checks := agent.RunPreflight(context.Background(), client, chainID, mevSimAddr, agents, relayUrl, relayHTTP)
+	if !agent.PrintPreflight(checks) {
+		if !*runSkipPreflight {
+			return fmt.Errorf("pre-flight checks failed, use -skip-preflight to run anyway")
+		}
+		fmt.Println("pre-flight checks failed, running anyway")
+	}
+
+	if *runTopUpThreshold > 0 && !*runDryRun {
+		fundedAgents := make([]funding.Agent, len(agents))
+		for i, a := range agents {
+			fundedAgents[i] = a
+		}
+		supervisor := funding.NewSupervisor(client, masterWallet, fundedAgents, big.NewInt(*runTopUpThreshold), big.NewInt(*runTopUpAmount))
+		go func() {
+			err := supervisor.Run(context.Background())
+			if err != nil {
+				fmt.Printf("error running funding supervisor: %v", err)
+			}
+		}()
+	}
+
+	agentAddresses := make([]common.Address, len(agents))
+	for i, a := range agents {
+		agentAddresses[i] = a.Address()
+	}
+	inclusionTracker, err := agent.NewInclusionTracker(client, mevSimAddr, slots, agentAddresses, valuations, observations)
+	if err != nil {
+		return err
+	}
+	go func() {
+		err := inclusionTracker.Run(context.Background())
+		if err != nil {
+			fmt.Printf("error running inclusion tracker: %v", err)
+		}
+	}()
+
+	if observations != nil && *runMempoolWs != "" {
+		mempoolWatcher, err := agent.NewMempoolWatcher(*runMempoolWs, chainID, mevSimAddr, observations)
+		if err != nil {
+			return err
+		}
+		go func() {
+			err := mempoolWatcher.Run(context.Background())
+			if err != nil {
+				fmt.Printf("error watching mempool: %v", err)
+			}
+		}()
+	}
+
+	if scheduler != nil {
+		go func() {
+			err := scheduler.Run(context.Background())
+			if err != nil {
+				fmt.Printf("error running scheduler: %v", err)
+			}
+		}()
+	}
+
+	if *runBatchState {
+		agentSlots := make([]*big.Int, len(agents))
+		for i, a := range agents {
+			agentSlots[i] = a.Slot
+		}
+		stateCoordinator, err := agent.NewStateCoordinator(node, mevSimAddr, agentSlots, agentAddresses, *runStatePoll)
+		if err != nil {
+			return err
+		}
+		for _, a := range agents {
+			a.State = stateCoordinator
+		}
+		go func() {
+			err := stateCoordinator.Run(context.Background())
+			if err != nil {
+				fmt.Printf("error running block state coordinator: %v", err)
+			}
+		}()
+	}
+
+	var (
+		relayClient relay.Client
+		resilient   *relay.Resilient
+		dryRun      *relay.DryRun
+	)
+	if *runDryRun {
+		out := dryRunStdout
+		if *runDryRunOut != "-" {
+			out, err = os.Create(*runDryRunOut)
+			if err != nil {
+				return err
+			}
+			defer out.Close()
+		}
+		dryRun = relay.NewDryRun(out)
+		relayClient = dryRun
+		if relayLimiter != nil {
+			relayClient = relay.NewRateLimited(dryRun, relayLimiter)
+		}
+		fmt.Println("dry run, bundles are written to", *runDryRunOut)
+	} else {
+		var httpRelay relay.Client = relay.NewHTTPClientWith(*runFlashbotsRpc, relayHTTP)
+		if relayLimiter != nil {
+			httpRelay = relay.NewRateLimited(httpRelay, relayLimiter)
 		}
+		resilient = relay.NewResilient(httpRelay, relay.DefaultPolicies(),
+			relay.NewCircuitBreaker(*runBreakerThreshold, *runBreakerCooldown, *runBreakerMaxCooldown))
+		relayClient = resilient
+	}
+	nodeStats := relay.NewStats()
+	if *runStatsInterval > 0 {
+		go func() {
+			for range time.Tick(*runStatsInterval) {
+				if resilient != nil {
+					fmt.Println("relay stats", resilient.Stats.String(), "breakerOpen", resilient.BreakerOpen())
+				} else {
+					fmt.Println("dry run bundles", dryRun.Written())
+				}
+				fmt.Println("node stats", nodeStats.String())
+				if scheduler != nil {
+					var granted []string
+					for i, slot := range slots {
+						granted = append(granted, fmt.Sprintf("%s=%d", slot.String(), scheduler.Granted(i)))
+					}
+					fmt.Println("scheduler granted", strings.Join(granted, " "))
+				}
+			}
+		}()
+	}
+
+	doneChan := make(chan struct{}, totalCount)
+	for _, a := range agents {
+		a := a
+		a.NodeStats = nodeStats
+		go func() {
+			// agents share node and relay clients, so their requests reuse pooled connections
+			err := a.Run(context.Background(), node, relayClient, mevSimAddr)
+			if err != nil {
+				fmt.Printf("error running agent: %v", err)
+			}
+			doneChan <- struct{}{}
+		}()
 	}
 
 	for i := 0; i < totalCount; i++ {
@@ -135,6 +740,216 @@ func ExecuteRunCmd(args []string) error {
 	return nil
 }
 
+// reserveStdout returns stdout for data output of a command, logs printed to stdout go to stderr from now on
+func reserveStdout() *os.File {
+	out := os.Stdout
+	os.Stdout = os.Stderr
+	return out
+}
+
+// dialNode connects to -rpc with shared transport and rpc endpoint options
+func dialNode() (*chain.NodeBackend, error) {
+	endpoint, err := newEndpoint(*rpcHeaders, *rpcBearer, *rpcJWTSecret, *rpcGzip)
+	if err != nil {
+		return nil, err
+	}
+	return chain.DialWithClient(*rpc, transport.NewClient(httpTransport, *rpcTimeout, endpoint))
+}
+
+// relayHTTPClient creates http client of relay endpoints with shared transport and relay endpoint options
+func relayHTTPClient(timeout time.Duration) (*http.Client, error) {
+	endpoint, err := newEndpoint(*relayHeaders, *relayBearer, *relayJWTSecret, *relayGzip)
+	if err != nil {
+		return nil, err
+	}
+	return transport.NewClient(httpTransport, timeout, endpoint), nil
+}
+
+func newEndpoint(headers string, bearer string, jwtSecretFile string, gzip bool) (transport.Endpoint, error) {
+	parsed, err := transport.ParseHeaders(headers)
+	if err != nil {
+		return transport.Endpoint{}, err
+	}
+	endpoint := transport.Endpoint{Headers: parsed, BearerToken: bearer, Gzip: gzip}
+	if jwtSecretFile != "" {
+		endpoint.JWTSecret, err = transport.LoadJWTSecret(jwtSecretFile)
+		if err != nil {
+			return transport.Endpoint{}, err
+		}
+	}
+	return endpoint, nil
+}
+
+func ExecuteRecordCmd(args []string) error {
+	err := recordCommand.Parse(args)
+	if err != nil {
+		recordCommand.Usage()
+		return err
+	}
+	out, err := os.OpenFile(*recordOut, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	upstreamHTTP, err := relayHTTPClient(*recordTimeout)
+	if err != nil {
+		return err
+	}
+	recorder := recording.NewRecorder(*recordUpstream, upstreamHTTP, out)
+	go func() {
+		var recorded, failed uint64
+		for range time.Tick(10 * time.Second) {
+			if count, failures := recorder.Recorded(), recorder.Failed(); count != recorded || failures != failed {
+				recorded, failed = count, failures
+				if failed > 0 {
+					fmt.Println("recorded bundles", recorded, "failed", failed, "recording is incomplete")
+				} else {
+					fmt.Println("recorded bundles", recorded)
+				}
+			}
+		}
+	}()
+	fmt.Println("recording", "listen", *recordListen, "upstream", *recordUpstream, "out", *recordOut)
+	return http.ListenAndServe(*recordListen, recorder)
+}
+
+func ExecuteReplayCmd(args []string) error {
+	err := replayCommand.Parse(args)
+	if err != nil {
+		replayCommand.Usage()
+		return err
+	}
+	requests, err := recording.Load(*replayIn)
+	if err != nil {
+		return err
+	}
+
+	var indices []int
+	if *replayHDIndices != "" {
+		indices, err = ParseIndexRanges(*replayHDIndices)
+	} else {
+		indices, err = IndexRange(*hdStartIndex, *replayCount)
+	}
+	if err != nil {
+		return err
+	}
+	var signers []wallet.TxSigner
+	if len(indices) > 0 {
+		_, signers, err = LoadWallets(indices)
+		if err != nil {
+			return err
+		}
+	}
+
+	client, err := dialNode()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	chainID, err := chain.VerifyChainID(context.Background(), client.Client, *expectedChainID)
+	if err != nil {
+		return err
+	}
+
+	relayHTTP, err := relayHTTPClient(*replayTimeout)
+	if err != nil {
+		return err
+	}
+	replayer, err := recording.NewReplayer(client, relay.NewHTTPClientWith(*replayTarget, relayHTTP), signers, recording.ReplayOptions{
+		Speed:       *replaySpeed,
+		Concurrency: *replayConcurrency,
+		Remap:       *replayRemap,
+		Resign:      *replayResign,
+		ChainID:     chainID,
+		MevSimAddr:  common.HexToAddress(*replayMevSimAddr),
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("replaying", "requests", len(requests), "target", *replayTarget, "speed", *replaySpeed)
+	return replayer.Replay(context.Background(), requests)
+}
+
+func ExecuteBenchCmd(args []string) error {
+	err := benchCommand.Parse(args)
+	if err != nil {
+		benchCommand.Usage()
+		return err
+	}
+	var benchStdout *os.File
+	if *benchOut == "-" {
+		// result is the only output on stdout, so it can be piped to json tools
+		benchStdout = reserveStdout()
+	}
+	var indices []int
+	if *benchHDIndices != "" {
+		indices, err = ParseIndexRanges(*benchHDIndices)
+	} else {
+		indices, err = IndexRange(*hdStartIndex, *benchCount)
+	}
+	if err != nil {
+		return err
+	}
+	_, signers, err := LoadWallets(indices)
+	if err != nil {
+		return err
+	}
+
+	client, err := dialNode()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	chainID, err := chain.VerifyChainID(context.Background(), client.Client, *expectedChainID)
+	if err != nil {
+		return err
+	}
+	bundles, err := bench.Presign(context.Background(), client, chainID, signers, common.HexToAddress(*benchMevSimAddr), big.NewInt(*benchSlot),
+		*benchBundles, *benchGasLimit, big.NewInt(int64(*benchTip*1e9)))
+	if err != nil {
+		return err
+	}
+
+	relayHTTP, err := relayHTTPClient(*benchTimeout)
+	if err != nil {
+		return err
+	}
+	b, err := bench.New(relay.NewHTTPClientWith(*benchTarget, relayHTTP), client, bundles, bench.Config{
+		StartRate:        *benchStartRate,
+		StartConcurrency: *benchStartConcurrency,
+		Factor:           *benchFactor,
+		MaxRate:          *benchMaxRate,
+		MaxConcurrency:   *benchMaxConcurrency,
+		StepDuration:     *benchStepDuration,
+		Refine:           *benchRefine,
+		SLO:              bench.SLO{P99Ms: float64(*benchSLOP99) / float64(time.Millisecond), ErrorRate: *benchSLOErrorRate},
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("benchmarking", "target", *benchTarget, "bundles", len(bundles), "signers", len(signers))
+	result, err := b.Run(context.Background(), *benchTarget)
+	if err != nil {
+		return err
+	}
+	if result.Knee != nil {
+		fmt.Println("knee", "rate", result.Knee.Rate, "concurrency", result.Knee.Concurrency, "throughput", fmt.Sprintf("%.1f", result.Knee.Throughput), "p99(ms)", result.Knee.P99)
+	} else {
+		fmt.Println("no step met the SLO")
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	if *benchOut == "-" {
+		_, err = fmt.Fprintln(benchStdout, string(data))
+		return err
+	}
+	return os.WriteFile(*benchOut, append(data, '\n'), 0644)
+}
+
 func ExecuteFundCmd(args []string) error {
 	err := fundCommand.Parse(args)
 	if err != nil {
@@ -144,20 +959,35 @@ func ExecuteFundCmd(args []string) error {
 
 	targetBalance := big.NewInt(*fundAmount)
 
-	client, err := ethclient.Dial(*rpc)
+	node, err := dialNode()
+	if err != nil {
+		return err
+	}
+	defer node.Close()
+	client := node.Client
+	_, err = chain.VerifyChainID(context.Background(), client, *expectedChainID)
 	if err != nil {
 		return err
 	}
 
-	masterWallet, agents, err := DeriveWallets(*mnemonic, *fundCount)
+	var indices []int
+	if *fundIndices != "" {
+		indices, err = ParseIndexRanges(*fundIndices)
+	} else {
+		indices, err = IndexRange(*hdStartIndex, *fundCount)
+	}
+	if err != nil {
+		return err
+	}
+	masterWallet, agents, err := LoadWallets(indices)
 	if err != nil {
 		return err
 	}
 	if *fundCheck {
-		privateKeys := append([]*ecdsa.PrivateKey{masterWallet}, agents...)
+		signers := append([]wallet.TxSigner{masterWallet}, agents...)
 		fmt.Printf("%-42s %-20s %-20s\n", "Address", "Balance(ETH)", "Defficit(ETH)")
-		for _, pk := range privateKeys {
-			address := crypto.PubkeyToAddress(pk.PublicKey)
+		for _, signer := range signers {
+			address := signer.Address()
 			balance, err := client.BalanceAt(context.Background(), address, nil)
 			if err != nil {
 				return err
@@ -166,28 +996,22 @@ func ExecuteFundCmd(args []string) error {
 			if deficit.Cmp(big.NewInt(0)) < 0 {
 				deficit = big.NewInt(0)
 			}
-			fmt.Printf("%-42s %-20s %-20s\n", address.Hex(), WeiToUnit(balance, 1e18).String(), WeiToUnit(deficit, 1e18).String())
+			fmt.Printf("%-42s %-20s %-20s\n", address.Hex(), chain.WeiToUnit(balance, 1e18).String(), chain.WeiToUnit(deficit, 1e18).String())
 		}
 		return nil
 	}
 
-	fundAmounts := make([]*big.Int, len(agents))
-	totalFundAmount := big.NewInt(0)
-	for i := 0; i < len(agents); i++ {
-		balance, err := client.BalanceAt(context.Background(), crypto.PubkeyToAddress(agents[i].PublicKey), nil)
-		if err != nil {
-			return err
-		}
-		sendValue := new(big.Int).Sub(targetBalance, balance)
-		if sendValue.Cmp(big.NewInt(0)) < 0 {
-			sendValue = big.NewInt(0)
-		}
-		fundAmounts[i] = sendValue
-		totalFundAmount = new(big.Int).Add(totalFundAmount, sendValue)
+	agentAddresses := make([]common.Address, len(agents))
+	for i, a := range agents {
+		agentAddresses[i] = a.Address()
+	}
+	fundAmounts, totalFundAmount, err := funding.Amounts(context.Background(), client, agentAddresses, targetBalance)
+	if err != nil {
+		return err
 	}
-	fmt.Printf("Total balance needed(eth): %s\n", WeiToUnit(totalFundAmount, 1e18).String())
+	fmt.Printf("Total balance needed(eth): %s\n", chain.WeiToUnit(totalFundAmount, 1e18).String())
 
-	balance, err := client.BalanceAt(context.Background(), crypto.PubkeyToAddress(masterWallet.PublicKey), nil)
+	balance, err := client.BalanceAt(context.Background(), masterWallet.Address(), nil)
 	if err != nil {
 		return err
 	}
@@ -195,61 +1019,118 @@ func ExecuteFundCmd(args []string) error {
 		return fmt.Errorf("master wallet balance insufficient")
 	}
 
-	chainID, err := client.NetworkID(context.Background())
+	sentTxs, err := funding.Send(context.Background(), client, masterWallet, agentAddresses, fundAmounts)
 	if err != nil {
 		return err
 	}
-	signer := types.NewLondonSigner(chainID)
-	nonce, err := client.PendingNonceAt(context.Background(), crypto.PubkeyToAddress(masterWallet.PublicKey))
-	if err != nil {
-		return err
+
+	if len(sentTxs) > 0 {
+		fmt.Println("Waiting for last transaction to be mined...")
+		_, err := bind.WaitMined(context.Background(), client, sentTxs[len(sentTxs)-1])
+		if err != nil {
+			return err
+		}
 	}
-	gasPrice, err := client.SuggestGasPrice(context.Background())
-	if err != nil {
-		return err
+
+	return nil
+}
+
+// LoadWallets loads master wallet and searcher wallets from the source selected by flags.
+// Master and searcher wallets are derived from mnemonic unless keystore, key file or clef is specified.
+// indices select searcher wallets: derivation indices of mnemonic wallets, clef accounts (account 0 is master wallet)
+// or positions of keys in key file or keystore directory counted from 1.
+func LoadWallets(indices []int) (wallet.TxSigner, []wallet.TxSigner, error) {
+	if *clefUrl != "" {
+		client, err := gethrpc.Dial(*clefUrl)
+		if err != nil {
+			return nil, nil, err
+		}
+		signers, err := wallet.ClefSigners(client)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(signers) == 0 {
+			return nil, nil, fmt.Errorf("clef has no accounts")
+		}
+		searchers := make([]wallet.TxSigner, len(indices))
+		for i, index := range indices {
+			if index < 1 || index >= len(signers) {
+				return nil, nil, fmt.Errorf("clef has no searcher account %d, it has %d accounts", index, len(signers))
+			}
+			searchers[i] = signers[index]
+		}
+		return signers[0], searchers, nil
 	}
-	gasTipCap, err := client.SuggestGasTipCap(context.Background())
-	if err != nil {
-		return err
+	if *searcherKeystoreDir != "" && *searcherKeyFile != "" {
+		return nil, nil, fmt.Errorf("searcher-keystore and searcher-keys can't be used together")
 	}
-	var lastTx *types.Transaction
-	for i := 0; i < len(agents); i++ {
-		if fundAmounts[i].Cmp(big.NewInt(0)) == 0 {
-			continue
+
+	var (
+		masterKey    *ecdsa.PrivateKey
+		searcherKeys []*ecdsa.PrivateKey
+	)
+	searchersFromMnemonic := *searcherKeystoreDir == "" && *searcherKeyFile == ""
+	if *keystoreFile == "" || searchersFromMnemonic {
+		phrase, err := readMnemonic()
+		if err != nil {
+			return nil, nil, err
+		}
+		var derivedIndices []int
+		if searchersFromMnemonic {
+			derivedIndices = indices
 		}
-		address := crypto.PubkeyToAddress(agents[i].PublicKey)
-		tx := types.NewTx(&types.DynamicFeeTx{
-			ChainID:   chainID,
-			Nonce:     nonce,
-			GasFeeCap: gasPrice,
-			GasTipCap: gasTipCap,
-			Gas:       21000,
-			To:        &address,
-			Value:     fundAmounts[i],
-		})
-		nonce++
-
-		signedTx, err := types.SignTx(tx, signer, masterWallet)
+		masterKey, searcherKeys, err = wallet.Derive(phrase, *hdPath, *hdMasterIndex, derivedIndices)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
-		fmt.Printf("Sending %s to %s, hash: %s\n", WeiToUnit(fundAmounts[i], 1e18).String(), address.Hex(), tx.Hash().Hex())
-		err = client.SendTransaction(context.Background(), signedTx)
+	}
+	password, err := wallet.ReadPassword(*passwordFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if *keystoreFile != "" {
+		masterKey, err = wallet.LoadKeystoreFile(*keystoreFile, password)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
-		lastTx = signedTx
 	}
-
-	if lastTx != nil {
-		fmt.Println("Waiting for last transaction to be mined...")
-		_, err := bind.WaitMined(context.Background(), client, lastTx)
+	if !searchersFromMnemonic {
+		var loadedKeys []*ecdsa.PrivateKey
+		if *searcherKeystoreDir != "" {
+			loadedKeys, err = wallet.LoadKeystoreDir(*searcherKeystoreDir, password)
+		} else {
+			loadedKeys, err = wallet.LoadKeyFile(*searcherKeyFile)
+		}
 		if err != nil {
-			return err
+			return nil, nil, err
+		}
+		searcherKeys = make([]*ecdsa.PrivateKey, len(indices))
+		for i, index := range indices {
+			if index < 1 || index > len(loadedKeys) {
+				return nil, nil, fmt.Errorf("no searcher key %d, %d keys loaded", index, len(loadedKeys))
+			}
+			searcherKeys[i] = loadedKeys[index-1]
 		}
 	}
+	return wallet.NewKeySigner(masterKey), wallet.NewKeySigners(searcherKeys), nil
+}
 
-	return nil
+// readMnemonic returns mnemonic from -mnemonic, -mnemonic-file or MNEMONIC environment variable
+func readMnemonic() (string, error) {
+	if *mnemonic != "" {
+		return *mnemonic, nil
+	}
+	if *mnemonicFile != "" {
+		phrase, err := os.ReadFile(*mnemonicFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(phrase)), nil
+	}
+	if phrase := os.Getenv("MNEMONIC"); phrase != "" {
+		return phrase, nil
+	}
+	return "", fmt.Errorf("mnemonic is not set, use -mnemonic, -mnemonic-file or MNEMONIC environment variable")
 }
 
 func init() {
@@ -265,11 +1146,18 @@ func init() {
 		fundCommand.PrintDefaults()
 		_, _ = fmt.Fprintf(os.Stderr, "deploy\n")
 		deployCommand.PrintDefaults()
+		_, _ = fmt.Fprintf(os.Stderr, "record\n")
+		recordCommand.PrintDefaults()
+		_, _ = fmt.Fprintf(os.Stderr, "replay\n")
+		replayCommand.PrintDefaults()
+		_, _ = fmt.Fprintf(os.Stderr, "bench\n")
+		benchCommand.PrintDefaults()
 	}
 }
 
 func main() {
 	flag.Parse()
+	httpTransport = transport.NewTransport(transport.Options{MaxIdleConnsPerHost: *httpMaxIdle, IdleConnTimeout: *httpIdleTimeout, HTTP2: *httpHTTP2})
 
 	args := flag.Args()
 	if len(args) < 1 {
@@ -294,6 +1182,21 @@ func main() {
 		if err != nil {
 			panic(err)
 		}
+	case "record":
+		err := ExecuteRecordCmd(commandArgs)
+		if err != nil {
+			panic(err)
+		}
+	case "replay":
+		err := ExecuteReplayCmd(commandArgs)
+		if err != nil {
+			panic(err)
+		}
+	case "bench":
+		err := ExecuteBenchCmd(commandArgs)
+		if err != nil {
+			panic(err)
+		}
 	default:
 		flag.Usage()
 	}
@@ -0,0 +1,196 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"golang.org/x/time/rate"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	ModeFair     = "fair"     // groups with waiting agents get tokens in turns
+	ModePriority = "priority" // group with the highest priority gets all tokens while it has waiting agents
+)
+
+func ParseMode(s string) (string, error) {
+	switch s {
+	case ModeFair, ModePriority:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown schedule mode %s, expected fair or priority", s)
+	}
+}
+
+// Scheduler shares tokens of rate limiters, e.g. global and per-relay, between groups of agents.
+// Every token is granted only when all limiters allow it, so total rate is held at the lowest limit
+// regardless of number of agents waiting.
+type Scheduler struct {
+	limiters   []*rate.Limiter
+	mode       string
+	priorities []int
+
+	mu sync.Mutex
+	// waiting agents by group, FIFO
+	queues [][]*waiter
+	// next group to get token in fair mode
+	next    int
+	notify  chan struct{}
+	granted []atomic.Uint64
+}
+
+type waiter struct {
+	ready    chan struct{}
+	canceled bool
+}
+
+// NewScheduler creates scheduler of groups with given priorities, higher priority is served first in priority mode
+func NewScheduler(mode string, priorities []int, limiters ...*rate.Limiter) *Scheduler {
+	return &Scheduler{
+		limiters:   limiters,
+		mode:       mode,
+		priorities: priorities,
+		queues:     make([][]*waiter, len(priorities)),
+		notify:     make(chan struct{}, 1),
+		granted:    make([]atomic.Uint64, len(priorities)),
+	}
+}
+
+// Wait blocks until group is granted a token
+func (s *Scheduler) Wait(ctx context.Context, group int) error {
+	if group < 0 || group >= len(s.queues) {
+		return fmt.Errorf("unknown scheduler group %d", group)
+	}
+	w := &waiter{ready: make(chan struct{})}
+	s.mu.Lock()
+	s.queues[group] = append(s.queues[group], w)
+	s.mu.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		select {
+		case <-w.ready:
+			// granted while canceling, token is used anyway
+			return nil
+		default:
+		}
+		w.canceled = true
+		return ctx.Err()
+	}
+}
+
+// Granted returns number of tokens granted to group
+func (s *Scheduler) Granted(group int) uint64 {
+	return s.granted[group].Load()
+}
+
+// Groups returns number of groups
+func (s *Scheduler) Groups() int {
+	return len(s.queues)
+}
+
+// Limit returns the lowest rate of limiters, the most tokens per second all groups get together
+func (s *Scheduler) Limit() rate.Limit {
+	limit := rate.Inf
+	for _, limiter := range s.limiters {
+		if limiter.Limit() < limit {
+			limit = limiter.Limit()
+		}
+	}
+	return limit
+}
+
+// Mode returns ModeFair or ModePriority
+func (s *Scheduler) Mode() string {
+	return s.mode
+}
+
+// Run grants tokens to waiting agents until ctx is canceled
+func (s *Scheduler) Run(ctx context.Context) error {
+	for {
+		if !s.hasWaiters() {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-s.notify:
+				continue
+			}
+		}
+		for _, limiter := range s.limiters {
+			err := limiter.Wait(ctx)
+			if err != nil {
+				return err
+			}
+		}
+		// token is kept for the next waiter if all waiters canceled meanwhile
+		for !s.grant() {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-s.notify:
+			}
+		}
+	}
+}
+
+func (s *Scheduler) hasWaiters() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for group := range s.queues {
+		s.dropCanceled(group)
+		if len(s.queues[group]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// grant gives token to the next waiter selected by mode, returns false if nobody is waiting
+func (s *Scheduler) grant() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	group := -1
+	switch s.mode {
+	case ModePriority:
+		for g := range s.queues {
+			s.dropCanceled(g)
+			if len(s.queues[g]) > 0 && (group == -1 || s.priorities[g] > s.priorities[group]) {
+				group = g
+			}
+		}
+	default:
+		for i := 0; i < len(s.queues); i++ {
+			g := (s.next + i) % len(s.queues)
+			s.dropCanceled(g)
+			if len(s.queues[g]) > 0 {
+				group = g
+				s.next = g + 1
+				break
+			}
+		}
+	}
+	if group == -1 {
+		return false
+	}
+	w := s.queues[group][0]
+	s.queues[group] = s.queues[group][1:]
+	close(w.ready)
+	s.granted[group].Add(1)
+	return true
+}
+
+func (s *Scheduler) dropCanceled(group int) {
+	queue := s.queues[group]
+	for len(queue) > 0 && queue[0].canceled {
+		queue = queue[1:]
+	}
+	s.queues[group] = queue
+}
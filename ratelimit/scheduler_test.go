@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"golang.org/x/time/rate"
+	"sync"
+	"testing"
+	"time"
+)
+
+// runAgents runs agents[g] agents of group g that take tokens as fast as scheduler grants them
+func runAgents(scheduler *Scheduler, agents []int, duration time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+	go func() { _ = scheduler.Run(ctx) }()
+
+	var wg sync.WaitGroup
+	for group, count := range agents {
+		for i := 0; i < count; i++ {
+			group := group
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for scheduler.Wait(ctx, group) == nil {
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+func TestSchedulerHoldsRate(t *testing.T) {
+	scheduler := NewScheduler(ModeFair, []int{0, 0}, rate.NewLimiter(100, 1), rate.NewLimiter(1000, 10))
+	runAgents(scheduler, []int{10, 10}, 500*time.Millisecond)
+
+	total := scheduler.Granted(0) + scheduler.Granted(1)
+	if total < 40 || total > 60 {
+		t.Errorf("granted %d tokens in 500ms at 100/s", total)
+	}
+}
+
+func TestSchedulerFairShare(t *testing.T) {
+	scheduler := NewScheduler(ModeFair, []int{0, 0}, rate.NewLimiter(200, 1))
+	runAgents(scheduler, []int{1, 20}, 500*time.Millisecond)
+
+	few, many := scheduler.Granted(0), scheduler.Granted(1)
+	if few == 0 || many == 0 || few+1 < many || many+1 < few {
+		t.Errorf("groups of 1 and 20 agents got %d and %d tokens, expected equal share", few, many)
+	}
+}
+
+func TestSchedulerPriority(t *testing.T) {
+	scheduler := NewScheduler(ModePriority, []int{0, 1}, rate.NewLimiter(200, 1))
+	runAgents(scheduler, []int{5, 5}, 300*time.Millisecond)
+
+	low, high := scheduler.Granted(0), scheduler.Granted(1)
+	if high == 0 || low > 1 {
+		t.Errorf("low and high priority groups got %d and %d tokens, expected all to high priority", low, high)
+	}
+}
@@ -0,0 +1,216 @@
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/metachris/flashbotsrpc"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Request is eth_sendBundle request captured by Recorder, recordings are files of requests as json lines
+type Request struct {
+	Time        time.Time         `json:"time"`
+	TargetBlock uint64            `json:"targetBlock"`
+	Headers     map[string]string `json:"headers"`
+	Body        json.RawMessage   `json:"body"`
+}
+
+type rpcRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+// Bundle decodes bundle sent by request
+func (r *Request) Bundle() (flashbotsrpc.FlashbotsSendBundleRequest, error) {
+	var bundle flashbotsrpc.FlashbotsSendBundleRequest
+	var req rpcRequest
+	err := json.Unmarshal(r.Body, &req)
+	if err != nil {
+		return bundle, err
+	}
+	if req.Method != "eth_sendBundle" || len(req.Params) != 1 {
+		return bundle, fmt.Errorf("not eth_sendBundle request with single param: %s", req.Method)
+	}
+	err = json.Unmarshal(req.Params[0], &bundle)
+	return bundle, err
+}
+
+// Load reads recording at path sorted by time
+func Load(path string) ([]Request, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var requests []Request
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var request Request
+		err = json.Unmarshal(scanner.Bytes(), &request)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		requests = append(requests, request)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(requests, func(i, j int) bool { return requests[i].Time.Before(requests[j].Time) })
+	return requests, nil
+}
+
+// Recorder is relay proxy that records eth_sendBundle requests and forwards all requests to upstream relay.
+// Without upstream bundles are accepted with their bundle hash and other methods fail.
+type Recorder struct {
+	upstream   string
+	httpClient *http.Client
+
+	mu  sync.Mutex
+	out io.Writer
+
+	recorded atomic.Uint64
+	// bundles forwarded but missing from the recording
+	failed atomic.Uint64
+}
+
+func NewRecorder(upstream string, httpClient *http.Client, out io.Writer) *Recorder {
+	return &Recorder{
+		upstream:   upstream,
+		httpClient: httpClient,
+		out:        out,
+	}
+}
+
+// Recorded returns number of recorded requests
+func (r *Recorder) Recorded() uint64 {
+	return r.recorded.Load()
+}
+
+// Failed returns number of bundle requests that couldn't be recorded, the recording is incomplete if it's not 0
+func (r *Recorder) Failed() uint64 {
+	return r.failed.Load()
+}
+
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var reader io.Reader = req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer zr.Close()
+		reader = zr
+		// recorded and forwarded decompressed, upstream client compresses it again if configured
+		req.Header.Del("Content-Encoding")
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var rpcReq rpcRequest
+	// batches and invalid requests are only forwarded
+	isRPC := json.Unmarshal(body, &rpcReq) == nil
+
+	if isRPC && rpcReq.Method == "eth_sendBundle" {
+		err = r.record(req, body)
+		if err != nil {
+			r.failed.Add(1)
+			fmt.Println("error recording request", err)
+		}
+	}
+
+	if r.upstream == "" {
+		r.respondLocally(w, rpcReq, body)
+		return
+	}
+	r.forward(w, req, body)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) error {
+	request := Request{
+		Time:    time.Now(),
+		Headers: make(map[string]string),
+		Body:    body,
+	}
+	for name := range req.Header {
+		request.Headers[name] = req.Header.Get(name)
+	}
+	bundle, err := request.Bundle()
+	if err != nil {
+		return err
+	}
+	request.TargetBlock, err = hexutil.DecodeUint64(bundle.BlockNumber)
+	if err != nil {
+		return fmt.Errorf("invalid block number %s: %w", bundle.BlockNumber, err)
+	}
+	line, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err = r.out.Write(append(line, '\n'))
+	if err != nil {
+		return err
+	}
+	r.recorded.Add(1)
+	return nil
+}
+
+func (r *Recorder) respondLocally(w http.ResponseWriter, rpcReq rpcRequest, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	id := rpcReq.ID
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	var resp interface{}
+	if rpcReq.Method == "eth_sendBundle" {
+		// bundle hash is hash of request body, there is no relay to compute the real one
+		resp = map[string]interface{}{"jsonrpc": "2.0", "id": id, "result": map[string]string{"bundleHash": crypto.Keccak256Hash(body).Hex()}}
+	} else {
+		resp = map[string]interface{}{"jsonrpc": "2.0", "id": id, "error": map[string]interface{}{"code": -32601, "message": "recorder has no upstream relay"}}
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (r *Recorder) forward(w http.ResponseWriter, req *http.Request, body []byte) {
+	upstreamReq, err := http.NewRequestWithContext(req.Context(), http.MethodPost, r.upstream, bytes.NewReader(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for name, values := range req.Header {
+		upstreamReq.Header[name] = values
+	}
+	resp, err := r.httpClient.Do(upstreamReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
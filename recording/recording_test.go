@@ -0,0 +1,152 @@
+package recording
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/metachris/flashbotsrpc"
+	"go-bundles-go/relay"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func signedTxHex(t *testing.T, key *ecdsa.PrivateKey, nonce uint64) string {
+	t.Helper()
+	tx, err := types.SignTx(types.NewTransaction(nonce, common.Address{1}, big.NewInt(0), 21000, big.NewInt(1e9), nil), types.HomesteadSigner{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txBytes, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hexutil.Encode(txBytes)
+}
+
+func TestRecorderCapturesAndForwards(t *testing.T) {
+	var mu sync.Mutex
+	var forwarded []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		forwarded = append(forwarded, string(body))
+		mu.Unlock()
+		if strings.Contains(string(body), "eth_callBundle") {
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"totalGasUsed":21000}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"bundleHash":"0x01"}}`))
+	}))
+	defer upstream.Close()
+
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	recorder := NewRecorder(upstream.URL, &http.Client{Timeout: time.Second}, out)
+	proxy := httptest.NewServer(recorder)
+	defer proxy.Close()
+
+	key, _ := crypto.GenerateKey()
+	client := relay.NewHTTPClient(proxy.URL, time.Second)
+	for block := 10; block < 12; block++ {
+		res, err := client.FlashbotsSendBundle(key, flashbotsrpc.FlashbotsSendBundleRequest{Txs: []string{signedTxHex(t, key, 0)}, BlockNumber: hexutil.EncodeUint64(uint64(block))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.BundleHash != "0x01" {
+			t.Errorf("bundle hash %s, expected upstream response", res.BundleHash)
+		}
+	}
+	_, err = client.FlashbotsCallBundle(key, flashbotsrpc.FlashbotsCallBundleParam{Txs: []string{signedTxHex(t, key, 0)}, BlockNumber: "0xa", StateBlockNumber: "latest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(forwarded) != 3 {
+		t.Fatalf("%d requests forwarded, expected 3", len(forwarded))
+	}
+	requests, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 || recorder.Recorded() != 2 {
+		t.Fatalf("%d requests recorded, expected 2 bundles", len(requests))
+	}
+	for i, request := range requests {
+		if request.TargetBlock != uint64(10+i) {
+			t.Errorf("request %d targets %d, expected %d", i, request.TargetBlock, 10+i)
+		}
+		if !bytes.Equal(request.Body, []byte(forwarded[i])) {
+			t.Errorf("request %d body %s, forwarded %s", i, request.Body, forwarded[i])
+		}
+		signature := request.Headers["X-Flashbots-Signature"]
+		if !strings.HasPrefix(signature, crypto.PubkeyToAddress(key.PublicKey).Hex()+":") {
+			t.Errorf("request %d signature header %q", i, signature)
+		}
+	}
+	if requests[1].Time.Before(requests[0].Time) {
+		t.Error("requests are not ordered by time")
+	}
+	if recorder.Failed() != 0 {
+		t.Errorf("%d requests failed to record", recorder.Failed())
+	}
+
+	// bundle that can't be recorded is still forwarded and counted as failed
+	_, err = client.FlashbotsSendBundle(key, flashbotsrpc.FlashbotsSendBundleRequest{Txs: []string{signedTxHex(t, key, 0)}, BlockNumber: "latest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recorder.Recorded() != 2 || recorder.Failed() != 1 {
+		t.Errorf("recorded %d failed %d, expected 2 recorded and 1 failed", recorder.Recorded(), recorder.Failed())
+	}
+
+	// without upstream bundles are accepted locally
+	local := httptest.NewServer(NewRecorder("", &http.Client{Timeout: time.Second}, io.Discard))
+	defer local.Close()
+	_, err = relay.NewHTTPClient(local.URL, time.Second).FlashbotsSendBundle(key, flashbotsrpc.FlashbotsSendBundleRequest{Txs: []string{signedTxHex(t, key, 0)}, BlockNumber: "0xa"})
+	if err != nil {
+		t.Errorf("bundle not accepted without upstream: %v", err)
+	}
+}
+
+func TestLoadReadsDryRunRequests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dryrun.jsonl")
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := crypto.GenerateKey()
+	_, err = relay.NewDryRun(out).FlashbotsSendBundle(key, flashbotsrpc.FlashbotsSendBundleRequest{Txs: []string{signedTxHex(t, key, 0)}, BlockNumber: "0xa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Close()
+
+	requests, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 || requests[0].TargetBlock != 10 {
+		t.Fatalf("loaded %+v, expected request for block 10", requests)
+	}
+	if _, err := requests[0].Bundle(); err != nil {
+		t.Error(err)
+	}
+	if signature := requests[0].Headers["X-Flashbots-Signature"]; !strings.HasPrefix(signature, crypto.PubkeyToAddress(key.PublicKey).Hex()+":") {
+		t.Errorf("signature header %q", signature)
+	}
+}
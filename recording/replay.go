@@ -0,0 +1,325 @@
+package recording
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"go-bundles-go/chain"
+	"go-bundles-go/contracts/mevsim"
+	"go-bundles-go/relay"
+	"go-bundles-go/wallet"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReplayOptions configures Replayer
+type ReplayOptions struct {
+	// Speed scales gaps between requests: 1 keeps original timing, 2 replays twice as fast, 0 sends as fast as possible
+	Speed float64
+	// Concurrency is number of requests in flight
+	Concurrency int
+	// Remap shifts target blocks so the first recorded target is the block after the replay chain head
+	Remap bool
+	// Resign rebuilds auction txs of known signers with replay chain nonces and slot values
+	Resign     bool
+	ChainID    *big.Int
+	MevSimAddr common.Address
+}
+
+type senderBlock struct {
+	sender common.Address
+	block  uint64
+}
+
+type slotBlock struct {
+	slot  string
+	block uint64
+}
+
+// Replayer resends recorded bundles to relay
+type Replayer struct {
+	client  chain.Backend
+	relay   relay.Client
+	signers map[common.Address]wallet.TxSigner
+	options ReplayOptions
+	mevsim  *mevsim.MevSimCaller
+
+	auctionIDs [][]byte
+	stats      *relay.Stats
+
+	mu sync.Mutex
+	// keys signing relay requests by recorded signature address
+	relayKeys map[common.Address]*ecdsa.PrivateKey
+	// replay chain values are read once per target block
+	nonces     map[senderBlock]uint64
+	slotValues map[slotBlock]*big.Int
+}
+
+// NewReplayer creates replayer sending to relayClient, signers are needed to re-sign txs and to sign requests of their recorded identity
+func NewReplayer(client chain.Backend, relayClient relay.Client, signers []wallet.TxSigner, options ReplayOptions) (*Replayer, error) {
+	if options.Concurrency < 1 {
+		options.Concurrency = 1
+	}
+	if options.Speed < 0 {
+		return nil, fmt.Errorf("replay speed must be >= 0")
+	}
+	caller, err := mevsim.NewMevSimCaller(options.MevSimAddr, client)
+	if err != nil {
+		return nil, err
+	}
+	mevsimAbi, err := mevsim.MevSimMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	r := &Replayer{
+		client:     client,
+		relay:      relayClient,
+		signers:    make(map[common.Address]wallet.TxSigner),
+		options:    options,
+		mevsim:     caller,
+		auctionIDs: [][]byte{mevsimAbi.Methods["auction"].ID, mevsimAbi.Methods["auctionWithBurn"].ID},
+		stats:      relay.NewStats(),
+		relayKeys:  make(map[common.Address]*ecdsa.PrivateKey),
+		nonces:     make(map[senderBlock]uint64),
+		slotValues: make(map[slotBlock]*big.Int),
+	}
+	for _, signer := range signers {
+		r.signers[signer.Address()] = signer
+	}
+	return r, nil
+}
+
+// Stats returns outcomes of replayed requests
+func (r *Replayer) Stats() *relay.Stats {
+	return r.stats
+}
+
+// Replay sends requests in order keeping their relative timing scaled by speed
+func (r *Replayer) Replay(ctx context.Context, requests []Request) error {
+	if len(requests) == 0 {
+		return nil
+	}
+	var offset int64
+	if r.options.Remap {
+		head, err := r.client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return err
+		}
+		offset = int64(head.Number.Uint64()+1) - int64(requests[0].TargetBlock)
+		fmt.Println("remapping target blocks", "recordedFirst", requests[0].TargetBlock, "replayFirst", head.Number.Uint64()+1, "offset", offset)
+	}
+
+	work := make(chan Request)
+	var wg sync.WaitGroup
+	for i := 0; i < r.options.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for request := range work {
+				err := r.send(ctx, request, offset)
+				r.stats.Record(err)
+				if err != nil {
+					fmt.Println("error replaying bundle", "recordedTarget", request.TargetBlock, "err", err)
+				}
+			}
+		}()
+	}
+
+	start := time.Now()
+	var err error
+	for _, request := range requests {
+		if r.options.Speed > 0 {
+			due := start.Add(time.Duration(float64(request.Time.Sub(requests[0].Time)) / r.options.Speed))
+			timer := time.NewTimer(time.Until(due))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+			case <-timer.C:
+			}
+		}
+		if ctx.Err() != nil {
+			err = ctx.Err()
+			break
+		}
+		work <- request
+	}
+	close(work)
+	wg.Wait()
+	fmt.Println("replay done", "requests", len(requests), "duration", time.Since(start).Round(time.Millisecond), "relay", r.stats.String())
+	return err
+}
+
+func (r *Replayer) send(ctx context.Context, request Request, offset int64) error {
+	bundle, err := request.Bundle()
+	if err != nil {
+		return err
+	}
+	targetBlock := uint64(int64(request.TargetBlock) + offset)
+	bundle.BlockNumber = hexutil.EncodeUint64(targetBlock)
+	if r.options.Resign {
+		// txs of one sender in the bundle take consecutive nonces after the nonce shared by bundles of the block
+		senderTxs := make(map[common.Address]uint64)
+		for i, txHex := range bundle.Txs {
+			bundle.Txs[i], err = r.resign(ctx, txHex, targetBlock, senderTxs)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	relayKey, err := r.relayKey(request.Headers)
+	if err != nil {
+		return err
+	}
+	_, err = r.relay.FlashbotsSendBundle(relayKey, bundle)
+	return err
+}
+
+// relayKey returns key of signer with the recorded signature address, or random key kept for the address
+// so requests of one recorded searcher share an identity
+func (r *Replayer) relayKey(headers map[string]string) (*ecdsa.PrivateKey, error) {
+	var address common.Address
+	for name, value := range headers {
+		if strings.EqualFold(name, "X-Flashbots-Signature") {
+			address = common.HexToAddress(strings.SplitN(value, ":", 2)[0])
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if key, ok := r.relayKeys[address]; ok {
+		return key, nil
+	}
+	var key *ecdsa.PrivateKey
+	var err error
+	if signer, ok := r.signers[address]; ok {
+		key, err = wallet.RelayKey(signer)
+	} else {
+		key, err = crypto.GenerateKey()
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.relayKeys[address] = key
+	return key, nil
+}
+
+// resign rebuilds tx with pending nonce of replay chain and, for auction calls, current slot value and remapped target block.
+// senderTxs counts txs of senders resigned earlier in the same bundle, their nonces follow.
+// Txs of unknown senders are sent unchanged.
+func (r *Replayer) resign(ctx context.Context, txHex string, targetBlock uint64, senderTxs map[common.Address]uint64) (string, error) {
+	txBytes, err := hexutil.Decode(txHex)
+	if err != nil {
+		return "", err
+	}
+	tx := new(types.Transaction)
+	err = tx.UnmarshalBinary(txBytes)
+	if err != nil {
+		return "", err
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return "", err
+	}
+	signer, ok := r.signers[sender]
+	if !ok {
+		return txHex, nil
+	}
+
+	nonce, err := r.nonce(ctx, sender, targetBlock)
+	if err != nil {
+		return "", err
+	}
+	nonce += senderTxs[sender]
+	senderTxs[sender]++
+	data := common.CopyBytes(tx.Data())
+	if r.isAuction(tx) {
+		slotValue, err := r.slotValue(ctx, new(big.Int).SetBytes(data[4:36]), targetBlock)
+		if err != nil {
+			return "", err
+		}
+		// auction(slot, value, target_block), auctionWithBurn starts with the same arguments
+		copy(data[36:68], common.LeftPadBytes(slotValue.Bytes(), 32))
+		copy(data[68:100], common.LeftPadBytes(new(big.Int).SetUint64(targetBlock).Bytes(), 32))
+	}
+
+	var unsigned *types.Transaction
+	switch tx.Type() {
+	case types.LegacyTxType:
+		unsigned = types.NewTx(&types.LegacyTx{Nonce: nonce, GasPrice: tx.GasPrice(), Gas: tx.Gas(), To: tx.To(), Value: tx.Value(), Data: data})
+	case types.AccessListTxType:
+		unsigned = types.NewTx(&types.AccessListTx{ChainID: r.options.ChainID, Nonce: nonce, GasPrice: tx.GasPrice(), Gas: tx.Gas(), To: tx.To(), Value: tx.Value(), Data: data, AccessList: tx.AccessList()})
+	default:
+		unsigned = types.NewTx(&types.DynamicFeeTx{ChainID: r.options.ChainID, Nonce: nonce, GasTipCap: tx.GasTipCap(), GasFeeCap: tx.GasFeeCap(), Gas: tx.Gas(), To: tx.To(), Value: tx.Value(), Data: data, AccessList: tx.AccessList()})
+	}
+	signedTx, err := signer.SignTx(unsigned, r.options.ChainID)
+	if err != nil {
+		return "", err
+	}
+	signedBytes, err := signedTx.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	return hexutil.Encode(signedBytes), nil
+}
+
+func (r *Replayer) isAuction(tx *types.Transaction) bool {
+	if tx.To() == nil || *tx.To() != r.options.MevSimAddr || len(tx.Data()) < 4+3*32 {
+		return false
+	}
+	for _, id := range r.auctionIDs {
+		if bytes.Equal(tx.Data()[:4], id) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Replayer) nonce(ctx context.Context, sender common.Address, targetBlock uint64) (uint64, error) {
+	key := senderBlock{sender: sender, block: targetBlock}
+	r.mu.Lock()
+	nonce, ok := r.nonces[key]
+	r.mu.Unlock()
+	if ok {
+		return nonce, nil
+	}
+	nonce, err := r.client.PendingNonceAt(ctx, sender)
+	if err != nil {
+		return 0, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// the first read of the block wins so all bids of the block share the nonce like recorded ones
+	if cached, ok := r.nonces[key]; ok {
+		return cached, nil
+	}
+	r.nonces[key] = nonce
+	return nonce, nil
+}
+
+func (r *Replayer) slotValue(ctx context.Context, slot *big.Int, targetBlock uint64) (*big.Int, error) {
+	key := slotBlock{slot: slot.String(), block: targetBlock}
+	r.mu.Lock()
+	value, ok := r.slotValues[key]
+	r.mu.Unlock()
+	if ok {
+		return value, nil
+	}
+	value, err := r.mevsim.GetSlot(&bind.CallOpts{Context: ctx}, slot)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cached, ok := r.slotValues[key]; ok {
+		return cached, nil
+	}
+	r.slotValues[key] = value
+	return value, nil
+}
@@ -0,0 +1,244 @@
+package recording
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/metachris/flashbotsrpc"
+	"go-bundles-go/chain"
+	"go-bundles-go/contracts/mevsim"
+	"go-bundles-go/internal/simchain"
+	"go-bundles-go/wallet"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+)
+
+// captureRelay keeps bundles and keys of replayed requests
+type captureRelay struct {
+	mu      sync.Mutex
+	bundles []flashbotsrpc.FlashbotsSendBundleRequest
+	keys    []common.Address
+}
+
+func (c *captureRelay) FlashbotsSendBundle(privKey *ecdsa.PrivateKey, param flashbotsrpc.FlashbotsSendBundleRequest) (flashbotsrpc.FlashbotsSendBundleResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bundles = append(c.bundles, param)
+	c.keys = append(c.keys, crypto.PubkeyToAddress(privKey.PublicKey))
+	return flashbotsrpc.FlashbotsSendBundleResponse{}, nil
+}
+
+func (c *captureRelay) FlashbotsCallBundle(privKey *ecdsa.PrivateKey, param flashbotsrpc.FlashbotsCallBundleParam) (flashbotsrpc.FlashbotsCallBundleResponse, error) {
+	return flashbotsrpc.FlashbotsCallBundleResponse{}, fmt.Errorf("not supported")
+}
+
+func TestReplayRemapsAndResigns(t *testing.T) {
+	master, _ := crypto.GenerateKey()
+	searcher, _ := crypto.GenerateKey()
+	searcherAddr := crypto.PubkeyToAddress(searcher.PublicKey)
+	balance := new(big.Int).Mul(big.NewInt(1000), big.NewInt(1e18))
+	backend := simchain.New(t, core.GenesisAlloc{
+		crypto.PubkeyToAddress(master.PublicKey): {Balance: balance},
+		searcherAddr:                             {Balance: balance},
+	})
+	ctx := context.Background()
+	chainID := backend.Blockchain().Config().ChainID
+	txSigner := types.LatestSignerForChainID(chainID)
+
+	head, err := backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployTx, err := types.SignTx(types.NewContractCreation(0, big.NewInt(0), 3000000, head.BaseFee, mevsim.Bytecode), txSigner, master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = backend.SendTransaction(ctx, deployTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Commit()
+	mevsimAddr := crypto.CreateAddress(crypto.PubkeyToAddress(master.PublicKey), 0)
+	mevsimAbi, err := mevsim.MevSimMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auctionTx := func(nonce uint64, slotValue int64, targetBlock uint64) *types.Transaction {
+		data, err := mevsimAbi.Pack("auction", big.NewInt(1), big.NewInt(slotValue), new(big.Int).SetUint64(targetBlock))
+		if err != nil {
+			t.Fatal(err)
+		}
+		head, err := backend.HeaderByNumber(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx, err := types.SignTx(chain.NewBidTx(types.DynamicFeeTxType, chainID, nonce, mevsimAddr, 200000, head.BaseFee, big.NewInt(1e9), big.NewInt(0), data, nil), txSigner, searcher)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+
+	// recorded on another chain: two bids for block 1000 and one for 1001, all with nonce 0 and slot value 0,
+	// the second bundle has another tx of the searcher with nonce 1
+	start := time.Now()
+	var requests []Request
+	for i, targetBlock := range []uint64{1000, 1000, 1001} {
+		txBytes, err := auctionTx(0, 0, targetBlock).MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs := []string{hexutil.Encode(txBytes)}
+		if i == 1 {
+			txBytes, err = auctionTx(1, 0, targetBlock).MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			txs = append(txs, hexutil.Encode(txBytes))
+		}
+		body, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "eth_sendBundle", "params": []interface{}{
+			flashbotsrpc.FlashbotsSendBundleRequest{Txs: txs, BlockNumber: hexutil.EncodeUint64(targetBlock)},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		requests = append(requests, Request{
+			Time:        start.Add(time.Duration(i) * 100 * time.Millisecond),
+			TargetBlock: targetBlock,
+			Headers:     map[string]string{"X-Flashbots-Signature": searcherAddr.Hex() + ":0x00"},
+			Body:        body,
+		})
+	}
+
+	// replay chain moved on: the searcher won slot 1 once, so nonce and slot value changed
+	head, err = backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = backend.SendTransaction(ctx, auctionTx(0, 0, head.Number.Uint64()+1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Commit()
+	mevsimCaller, err := mevsim.NewMevSimCaller(mevsimAddr, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slotValue, err := mevsimCaller.GetSlot(&bind.CallOpts{}, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slotValue.Int64() != 1 {
+		t.Fatalf("slot value %s after winning, expected 1", slotValue)
+	}
+	head, err = backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	capture := &captureRelay{}
+	replayer, err := NewReplayer(backend, capture, []wallet.TxSigner{wallet.NewKeySigner(searcher)}, ReplayOptions{
+		Speed:       2,
+		Concurrency: 1,
+		Remap:       true,
+		Resign:      true,
+		ChainID:     chainID,
+		MevSimAddr:  mevsimAddr,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayStart := time.Now()
+	err = replayer.Replay(ctx, requests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 200ms of recording at double speed
+	if elapsed := time.Since(replayStart); elapsed < 100*time.Millisecond {
+		t.Errorf("replay took %s, expected at least 100ms", elapsed)
+	}
+	if replayer.Stats().Ok() != 3 {
+		t.Errorf("replay stats %s, expected 3 ok", replayer.Stats())
+	}
+
+	if len(capture.bundles) != 3 {
+		t.Fatalf("%d bundles replayed, expected 3", len(capture.bundles))
+	}
+	firstTarget := head.Number.Uint64() + 1
+	for i, expectedTarget := range []uint64{firstTarget, firstTarget, firstTarget + 1} {
+		bundle := capture.bundles[i]
+		if bundle.BlockNumber != hexutil.EncodeUint64(expectedTarget) {
+			t.Errorf("bundle %d targets %s, expected %d", i, bundle.BlockNumber, expectedTarget)
+		}
+		if capture.keys[i] != searcherAddr {
+			t.Errorf("bundle %d signed by relay key %s, expected searcher key", i, capture.keys[i].Hex())
+		}
+		txBytes, err := hexutil.Decode(bundle.Txs[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx := new(types.Transaction)
+		err = tx.UnmarshalBinary(txBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sender, err := types.Sender(txSigner, tx)
+		if err != nil || sender != searcherAddr {
+			t.Errorf("bundle %d tx sender %s, err %v", i, sender.Hex(), err)
+		}
+		if tx.Nonce() != 1 {
+			t.Errorf("bundle %d tx nonce %d, expected 1", i, tx.Nonce())
+		}
+		args, err := mevsimAbi.Methods["auction"].Inputs.Unpack(tx.Data()[4:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value := args[1].(*big.Int); value.Int64() != 1 {
+			t.Errorf("bundle %d bids for slot value %s, expected 1", i, value)
+		}
+		if target := args[2].(*big.Int); target.Uint64() != expectedTarget {
+			t.Errorf("bundle %d auction targets %s, expected %d", i, target, expectedTarget)
+		}
+	}
+
+	// txs of one sender in a bundle get consecutive nonces
+	txBytes, err := hexutil.Decode(capture.bundles[1].Txs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := new(types.Transaction)
+	err = second.UnmarshalBinary(txBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Nonce() != 2 {
+		t.Errorf("second tx of the bundle has nonce %d, expected 2", second.Nonce())
+	}
+
+	// the first replayed bundle is valid on the replay chain
+	txBytes, _ = hexutil.Decode(capture.bundles[0].Txs[0])
+	tx := new(types.Transaction)
+	_ = tx.UnmarshalBinary(txBytes)
+	err = backend.SendTransaction(ctx, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Commit()
+	receipt, err := backend.TransactionReceipt(ctx, tx.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		t.Error("replayed auction tx reverted")
+	}
+}
@@ -0,0 +1,106 @@
+package relay
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitBreaker stops requests to relay after threshold consecutive failures.
+// It stays open for cooldown, which doubles every time the trial request after cooldown fails, up to maxCooldown.
+type CircuitBreaker struct {
+	threshold   int
+	cooldown    time.Duration
+	maxCooldown time.Duration
+
+	mu              sync.Mutex
+	failures        int
+	currentCooldown time.Duration
+	openUntil       time.Time
+	// trial request is in flight after cooldown
+	probing bool
+}
+
+func NewCircuitBreaker(threshold int, cooldown, maxCooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold:       threshold,
+		cooldown:        cooldown,
+		maxCooldown:     maxCooldown,
+		currentCooldown: cooldown,
+	}
+}
+
+// Allow reports if request can be sent, after cooldown only one trial request is allowed until it completes
+func (b *CircuitBreaker) Allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold && time.Now().After(b.openUntil) {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *CircuitBreaker) Success() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.probing = false
+	b.currentCooldown = b.cooldown
+}
+
+// Failure records failure and returns true if it opened the breaker
+func (b *CircuitBreaker) Failure() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.probing {
+		b.probing = false
+		b.currentCooldown *= 2
+		if b.currentCooldown > b.maxCooldown {
+			b.currentCooldown = b.maxCooldown
+		}
+		b.openUntil = time.Now().Add(b.currentCooldown)
+		return true
+	}
+	if b.failures == b.threshold {
+		b.openUntil = time.Now().Add(b.currentCooldown)
+		return true
+	}
+	return false
+}
+
+// OpenFor opens breaker for at least d, e.g. for Retry-After of rate limited relay
+func (b *CircuitBreaker) OpenFor(d time.Duration) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		b.failures = b.threshold
+	}
+	if until := time.Now().Add(d); until.After(b.openUntil) {
+		b.openUntil = until
+	}
+}
+
+func (b *CircuitBreaker) Open() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Now().Before(b.openUntil)
+}
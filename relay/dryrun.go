@@ -0,0 +1,99 @@
+package relay
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/metachris/flashbotsrpc"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DryRun writes bundles as json-rpc requests, one per line, instead of sending them.
+// Requests are the same HTTPClient would send with their X-Flashbots-Signature, written in the format
+// of recording.Request, so they can be replayed or used as fixtures.
+type DryRun struct {
+	mu sync.Mutex
+	w  io.Writer
+
+	written atomic.Uint64
+}
+
+// dryRunRequest is written request, it has json fields of recording.Request
+type dryRunRequest struct {
+	Time        time.Time         `json:"time"`
+	TargetBlock uint64            `json:"targetBlock"`
+	Headers     map[string]string `json:"headers"`
+	Body        json.RawMessage   `json:"body"`
+}
+
+func NewDryRun(w io.Writer) *DryRun {
+	return &DryRun{w: w}
+}
+
+// FlashbotsSendBundle writes eth_sendBundle request and returns bundle hash relay would return
+func (d *DryRun) FlashbotsSendBundle(privKey *ecdsa.PrivateKey, param flashbotsrpc.FlashbotsSendBundleRequest) (flashbotsrpc.FlashbotsSendBundleResponse, error) {
+	body, err := requestBody("eth_sendBundle", param)
+	if err != nil {
+		return flashbotsrpc.FlashbotsSendBundleResponse{}, err
+	}
+	signature, err := signatureHeader(privKey, body)
+	if err != nil {
+		return flashbotsrpc.FlashbotsSendBundleResponse{}, err
+	}
+	targetBlock, err := hexutil.DecodeUint64(param.BlockNumber)
+	if err != nil {
+		return flashbotsrpc.FlashbotsSendBundleResponse{}, fmt.Errorf("invalid block number %s: %w", param.BlockNumber, err)
+	}
+	line, err := json.Marshal(dryRunRequest{
+		Time:        time.Now(),
+		TargetBlock: targetBlock,
+		Headers: map[string]string{
+			"Content-Type":          "application/json",
+			"Accept":                "application/json",
+			"X-Flashbots-Signature": signature,
+		},
+		Body: body,
+	})
+	if err != nil {
+		return flashbotsrpc.FlashbotsSendBundleResponse{}, err
+	}
+	// bundle hash is hash of concatenated tx hashes
+	var txHashes []byte
+	for _, txHex := range param.Txs {
+		txBytes, err := hexutil.Decode(txHex)
+		if err != nil {
+			return flashbotsrpc.FlashbotsSendBundleResponse{}, err
+		}
+		tx := new(types.Transaction)
+		err = tx.UnmarshalBinary(txBytes)
+		if err != nil {
+			return flashbotsrpc.FlashbotsSendBundleResponse{}, err
+		}
+		txHashes = append(txHashes, tx.Hash().Bytes()...)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, err = d.w.Write(append(line, '\n'))
+	if err != nil {
+		return flashbotsrpc.FlashbotsSendBundleResponse{}, err
+	}
+	d.written.Add(1)
+	return flashbotsrpc.FlashbotsSendBundleResponse{BundleHash: crypto.Keccak256Hash(txHashes).Hex()}, nil
+}
+
+// FlashbotsCallBundle fails, bundle simulation needs the relay
+func (d *DryRun) FlashbotsCallBundle(privKey *ecdsa.PrivateKey, param flashbotsrpc.FlashbotsCallBundleParam) (flashbotsrpc.FlashbotsCallBundleResponse, error) {
+	return flashbotsrpc.FlashbotsCallBundleResponse{}, fmt.Errorf("eth_callBundle is not available in dry run")
+}
+
+// Written returns number of written requests
+func (d *DryRun) Written() uint64 {
+	return d.written.Load()
+}
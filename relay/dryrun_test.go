@@ -0,0 +1,77 @@
+package relay
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/metachris/flashbotsrpc"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDryRunWritesRequestsHTTPClientSends(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	tx, err := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(0), 21000, big.NewInt(1e9), nil), types.HomesteadSigner{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txBytes, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle := flashbotsrpc.FlashbotsSendBundleRequest{Txs: []string{hexutil.Encode(txBytes)}, BlockNumber: "0x10"}
+
+	var (
+		sent      []byte
+		signature string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get("X-Flashbots-Signature")
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"bundleHash":"0x01"}}`))
+	}))
+	defer server.Close()
+	_, err = NewHTTPClient(server.URL, time.Second).FlashbotsSendBundle(key, bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	dryRun := NewDryRun(&out)
+	for i := 0; i < 2; i++ {
+		res, err := dryRun.FlashbotsSendBundle(key, bundle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := crypto.Keccak256Hash(tx.Hash().Bytes()).Hex(); res.BundleHash != expected {
+			t.Errorf("bundle hash %s, expected %s", res.BundleHash, expected)
+		}
+	}
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	if len(lines) != 2 || dryRun.Written() != 2 {
+		t.Fatalf("dry run wrote %d requests:\n%s\nexpected 2", dryRun.Written(), out.String())
+	}
+	for _, line := range lines {
+		var request dryRunRequest
+		err = json.Unmarshal(line, &request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(request.Body, sent) {
+			t.Errorf("dry run request body %s, expected %s", request.Body, sent)
+		}
+		if request.Headers["X-Flashbots-Signature"] != signature {
+			t.Errorf("dry run signature %q, expected %q", request.Headers["X-Flashbots-Signature"], signature)
+		}
+		if request.TargetBlock != 0x10 {
+			t.Errorf("dry run request targets %d, expected 16", request.TargetBlock)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/metachris/flashbotsrpc"
+	"net"
+	"strings"
+	"time"
+)
+
+// ErrorClass groups errors of relay and node requests by how they should be handled
+type ErrorClass string
+
+const (
+	ClassTransport     ErrorClass = "transport"      // connection failures and timeouts
+	ClassRateLimited   ErrorClass = "rate-limited"   // http 429
+	ClassServer        ErrorClass = "server"         // http 5xx
+	ClassRPC           ErrorClass = "rpc"            // json-rpc errors not covered by other classes
+	ClassSignature     ErrorClass = "signature"      // X-Flashbots-Signature rejected
+	ClassBundleInvalid ErrorClass = "bundle-invalid" // relay rejected bundle content
+	ClassCircuitOpen   ErrorClass = "circuit-open"   // request not sent because relay circuit breaker is open
+	ClassUnknown       ErrorClass = "unknown"
+)
+
+var Classes = []ErrorClass{ClassTransport, ClassRateLimited, ClassServer, ClassRPC, ClassSignature, ClassBundleInvalid, ClassCircuitOpen, ClassUnknown}
+
+// Error is classified relay request error
+type Error struct {
+	Class      ErrorClass
+	StatusCode int // http status, 0 if response wasn't received
+	Code       int // json-rpc error code, 0 if there was no json-rpc error
+	Message    string
+	// RetryAfter is delay requested by relay with 429 response, 0 if not set
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if e.Err != nil {
+		msg = e.Err.Error()
+	}
+	return fmt.Sprintf("relay %s error (status %d, code %d): %s", e.Class, e.StatusCode, e.Code, msg)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Classify returns class of error returned by relay client or node rpc client
+func Classify(err error) ErrorClass {
+	if err == nil {
+		return ""
+	}
+	var relayErr *Error
+	if errors.As(err, &relayErr) {
+		return relayErr.Class
+	}
+	var httpErr gethrpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return classifyStatus(httpErr.StatusCode, string(httpErr.Body))
+	}
+	var rpcErr gethrpc.Error
+	if errors.As(err, &rpcErr) {
+		return classifyCode(rpcErr.ErrorCode(), rpcErr.Error())
+	}
+	if errors.Is(err, flashbotsrpc.ErrRelayErrorResponse) {
+		return classifyMessage(err.Error())
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) {
+		return ClassTransport
+	}
+	return ClassUnknown
+}
+
+// classifyStatus classifies non 200 http responses
+func classifyStatus(status int, body string) ErrorClass {
+	switch {
+	case status == 429:
+		return ClassRateLimited
+	case status >= 500:
+		return ClassServer
+	case status == 401 || status == 403:
+		return ClassSignature
+	case status == 400:
+		return classifyMessage(body)
+	default:
+		return ClassRPC
+	}
+}
+
+// classifyMessage classifies plain relay error responses such as {"error": "..."}
+func classifyMessage(msg string) ErrorClass {
+	if strings.Contains(strings.ToLower(msg), "signature") {
+		return ClassSignature
+	}
+	return ClassBundleInvalid
+}
+
+// classifyCode classifies json-rpc error codes
+func classifyCode(code int, msg string) ErrorClass {
+	switch code {
+	case -32600, -32602: // invalid request, invalid params
+		return classifyMessage(msg)
+	case -32005: // limit exceeded
+		return ClassRateLimited
+	default:
+		if strings.Contains(strings.ToLower(msg), "signature") {
+			return ClassSignature
+		}
+		return ClassRPC
+	}
+}
@@ -0,0 +1,165 @@
+package relay
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/metachris/flashbotsrpc"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultTimeout is timeout of a single relay request
+const DefaultTimeout = 10 * time.Second
+
+// HTTPClient sends flashbots signed json-rpc requests to relay and returns *Error with class of the failure.
+// Unlike flashbotsrpc it keeps http status and json-rpc error code and reuses connections.
+type HTTPClient struct {
+	url        string
+	httpClient *http.Client
+}
+
+func NewHTTPClient(url string, timeout time.Duration) *HTTPClient {
+	return NewHTTPClientWith(url, &http.Client{Timeout: timeout})
+}
+
+// NewHTTPClientWith creates client sending requests with httpClient, e.g. one sharing connections with other clients
+func NewHTTPClientWith(url string, httpClient *http.Client) *HTTPClient {
+	return &HTTPClient{
+		url:        url,
+		httpClient: httpClient,
+	}
+}
+
+type rpcRequest struct {
+	ID      int           `json:"id"`
+	JSONRPC string        `json:"jsonrpc"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	// json-rpc error object or plain string of relay error response
+	Error json.RawMessage `json:"error"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (c *HTTPClient) FlashbotsSendBundle(privKey *ecdsa.PrivateKey, param flashbotsrpc.FlashbotsSendBundleRequest) (res flashbotsrpc.FlashbotsSendBundleResponse, err error) {
+	err = c.call(privKey, "eth_sendBundle", param, &res)
+	return res, err
+}
+
+func (c *HTTPClient) FlashbotsCallBundle(privKey *ecdsa.PrivateKey, param flashbotsrpc.FlashbotsCallBundleParam) (res flashbotsrpc.FlashbotsCallBundleResponse, err error) {
+	err = c.call(privKey, "eth_callBundle", param, &res)
+	return res, err
+}
+
+// requestBody encodes json-rpc request of method with single param
+func requestBody(method string, param interface{}) ([]byte, error) {
+	return json.Marshal(rpcRequest{ID: 1, JSONRPC: "2.0", Method: method, Params: []interface{}{param}})
+}
+
+// signatureHeader returns X-Flashbots-Signature of request body signed by privKey
+func signatureHeader(privKey *ecdsa.PrivateKey, body []byte) (string, error) {
+	hashedBody := crypto.Keccak256Hash(body).Hex()
+	sig, err := crypto.Sign(accounts.TextHash([]byte(hashedBody)), privKey)
+	if err != nil {
+		return "", err
+	}
+	return crypto.PubkeyToAddress(privKey.PublicKey).Hex() + ":" + hexutil.Encode(sig), nil
+}
+
+func (c *HTTPClient) call(privKey *ecdsa.PrivateKey, method string, param interface{}, result interface{}) error {
+	body, err := requestBody(method, param)
+	if err != nil {
+		return err
+	}
+	signature, err := signatureHeader(privKey, body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Flashbots-Signature", signature)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return &Error{Class: ClassTransport, Err: err}
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &Error{Class: ClassTransport, StatusCode: resp.StatusCode, Err: err}
+	}
+
+	var rpcResp rpcResponse
+	decodeErr := json.Unmarshal(data, &rpcResp)
+
+	if resp.StatusCode != http.StatusOK {
+		relayErr := &Error{
+			Class:      classifyStatus(resp.StatusCode, string(data)),
+			StatusCode: resp.StatusCode,
+			Message:    fmt.Sprintf("%s: %s", resp.Status, bytes.TrimSpace(data)),
+		}
+		if decodeErr == nil {
+			relayErr.Code, _ = parseRPCError(rpcResp.Error)
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+				relayErr.RetryAfter = time.Duration(seconds) * time.Second
+			}
+		}
+		return relayErr
+	}
+	if decodeErr != nil {
+		return &Error{Class: ClassRPC, StatusCode: resp.StatusCode, Message: "invalid response", Err: decodeErr}
+	}
+	if len(rpcResp.Error) > 0 && string(rpcResp.Error) != "null" {
+		code, msg := parseRPCError(rpcResp.Error)
+		class := classifyMessage(msg)
+		if code != 0 {
+			class = classifyCode(code, msg)
+		}
+		return &Error{Class: class, StatusCode: resp.StatusCode, Code: code, Message: msg}
+	}
+	if result == nil {
+		return nil
+	}
+	err = json.Unmarshal(rpcResp.Result, result)
+	if err != nil {
+		return &Error{Class: ClassRPC, StatusCode: resp.StatusCode, Message: "invalid result", Err: err}
+	}
+	return nil
+}
+
+// parseRPCError parses json-rpc error object or plain error string
+func parseRPCError(raw json.RawMessage) (int, string) {
+	if len(raw) == 0 {
+		return 0, ""
+	}
+	var obj rpcError
+	if err := json.Unmarshal(raw, &obj); err == nil {
+		return obj.Code, obj.Message
+	}
+	var msg string
+	if err := json.Unmarshal(raw, &msg); err == nil {
+		return 0, msg
+	}
+	return 0, string(raw)
+}
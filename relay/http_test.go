@@ -0,0 +1,193 @@
+package relay
+
+import (
+	"errors"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/metachris/flashbotsrpc"
+	"golang.org/x/time/rate"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPClientClassifiesErrors(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	tests := []struct {
+		name    string
+		status  int
+		header  map[string]string
+		body    string
+		class   ErrorClass
+		code    int
+		waitFor time.Duration
+	}{
+		{name: "rate limited", status: 429, header: map[string]string{"Retry-After": "2"}, body: "too many requests", class: ClassRateLimited, waitFor: 2 * time.Second},
+		{name: "server", status: 503, body: "unavailable", class: ClassServer},
+		{name: "forbidden", status: 403, body: `{"error":"forbidden"}`, class: ClassSignature},
+		{name: "bad signature", status: 400, body: `{"error":"invalid flashbots signature"}`, class: ClassSignature},
+		{name: "bad bundle", status: 400, body: `{"error":"unable to decode txs"}`, class: ClassBundleInvalid},
+		{name: "invalid params", status: 200, body: `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"invalid params"}}`, class: ClassBundleInvalid, code: -32602},
+		{name: "rpc error", status: 200, body: `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"internal error"}}`, class: ClassRPC, code: -32000},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range test.header {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(test.status)
+				_, _ = w.Write([]byte(test.body))
+			}))
+			defer server.Close()
+
+			_, err := NewHTTPClient(server.URL, time.Second).FlashbotsSendBundle(key, flashbotsrpc.FlashbotsSendBundleRequest{Txs: []string{"0x00"}, BlockNumber: "0x1"})
+			var relayErr *Error
+			if !errors.As(err, &relayErr) {
+				t.Fatalf("expected relay error, got %v", err)
+			}
+			if relayErr.Class != test.class || relayErr.Code != test.code || relayErr.RetryAfter != test.waitFor {
+				t.Errorf("got class %s code %d retry after %s, expected %s %d %s", relayErr.Class, relayErr.Code, relayErr.RetryAfter, test.class, test.code, test.waitFor)
+			}
+		})
+	}
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	_, err := NewHTTPClient(server.URL, time.Second).FlashbotsSendBundle(key, flashbotsrpc.FlashbotsSendBundleRequest{})
+	if class := Classify(err); class != ClassTransport {
+		t.Errorf("closed server error class %s, expected %s", class, ClassTransport)
+	}
+}
+
+func TestHTTPClientSignsRequests(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		address, sigHex, _ := strings.Cut(r.Header.Get("X-Flashbots-Signature"), ":")
+		sig, err := hexutil.Decode(sigHex)
+		if err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		pub, err := crypto.SigToPub(accounts.TextHash([]byte(crypto.Keccak256Hash(body).Hex())), sig)
+		if err != nil || crypto.PubkeyToAddress(*pub).Hex() != address {
+			w.WriteHeader(403)
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"bundleHash":"0x01"}}`))
+	}))
+	defer server.Close()
+
+	res, err := NewHTTPClient(server.URL, time.Second).FlashbotsSendBundle(key, flashbotsrpc.FlashbotsSendBundleRequest{Txs: []string{"0x00"}, BlockNumber: "0x1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.BundleHash != "0x01" {
+		t.Errorf("bundle hash %s, expected 0x01", res.BundleHash)
+	}
+}
+
+func TestResilientCircuitBreaker(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	var (
+		requests atomic.Int64
+		healthy  atomic.Bool
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(503)
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"bundleHash":"0x01"}}`))
+	}))
+	defer server.Close()
+
+	policies := DefaultPolicies()
+	policies[ClassServer] = Policy{TripsBreaker: true}
+	client := NewResilient(NewHTTPClient(server.URL, time.Second), policies, NewCircuitBreaker(3, 50*time.Millisecond, time.Second))
+	send := func() error {
+		_, err := client.FlashbotsSendBundle(key, flashbotsrpc.FlashbotsSendBundleRequest{Txs: []string{"0x00"}, BlockNumber: "0x1"})
+		return err
+	}
+
+	for i := 0; i < 5; i++ {
+		_ = send()
+	}
+	if requests.Load() != 3 {
+		t.Errorf("relay got %d requests, expected 3 before breaker opened", requests.Load())
+	}
+	if client.Stats.Count(ClassServer) != 3 || client.Stats.Count(ClassCircuitOpen) != 2 {
+		t.Errorf("unexpected stats %s", client.Stats.String())
+	}
+	if !client.BreakerOpen() {
+		t.Error("breaker is closed after failures")
+	}
+
+	healthy.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	if err := send(); err != nil {
+		t.Fatalf("trial request after cooldown failed: %v", err)
+	}
+	if err := send(); err != nil {
+		t.Fatalf("request after recovery failed: %v", err)
+	}
+	if client.Stats.Ok() != 2 {
+		t.Errorf("unexpected stats %s", client.Stats.String())
+	}
+}
+
+func TestRateLimitedHoldsRetriesToRate(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	var (
+		mu       sync.Mutex
+		received []time.Time
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received = append(received, time.Now())
+		count := len(received)
+		mu.Unlock()
+		// every request fails once and succeeds on retry
+		if count%2 == 1 {
+			w.WriteHeader(503)
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"bundleHash":"0x01"}}`))
+	}))
+	defer server.Close()
+
+	policies := DefaultPolicies()
+	policies[ClassServer] = Policy{MaxRetries: 1}
+	const limit = 20
+	limited := NewRateLimited(NewHTTPClient(server.URL, time.Second), rate.NewLimiter(limit, 1))
+	client := NewResilient(limited, policies, NewCircuitBreaker(100, time.Second, time.Second))
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		_, err := client.FlashbotsSendBundle(key, flashbotsrpc.FlashbotsSendBundleRequest{Txs: []string{"0x00"}, BlockNumber: "0x1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, _ = CallBundleGasUsed(client, key, types.NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil), 1)
+
+	mu.Lock()
+	defer mu.Unlock()
+	// 5 bundles and a simulation with a retry each, the first request takes the burst token
+	if len(received) != 12 {
+		t.Fatalf("relay got %d requests, expected 12", len(received))
+	}
+	if elapsed, min := time.Since(start), 11*time.Second/limit; elapsed < min {
+		t.Errorf("12 requests sent in %s, expected at least %s at %d/s", elapsed, min, limit)
+	}
+}
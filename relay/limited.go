@@ -0,0 +1,35 @@
+package relay
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"github.com/metachris/flashbotsrpc"
+	"golang.org/x/time/rate"
+)
+
+// RateLimited holds requests of client to the rate of limiter. Every request on the wire takes a token,
+// including retries of Resilient wrapped around it and eth_callBundle simulations.
+type RateLimited struct {
+	client  Client
+	limiter *rate.Limiter
+}
+
+func NewRateLimited(client Client, limiter *rate.Limiter) *RateLimited {
+	return &RateLimited{client: client, limiter: limiter}
+}
+
+func (r *RateLimited) FlashbotsSendBundle(privKey *ecdsa.PrivateKey, param flashbotsrpc.FlashbotsSendBundleRequest) (flashbotsrpc.FlashbotsSendBundleResponse, error) {
+	err := r.limiter.Wait(context.Background())
+	if err != nil {
+		return flashbotsrpc.FlashbotsSendBundleResponse{}, err
+	}
+	return r.client.FlashbotsSendBundle(privKey, param)
+}
+
+func (r *RateLimited) FlashbotsCallBundle(privKey *ecdsa.PrivateKey, param flashbotsrpc.FlashbotsCallBundleParam) (flashbotsrpc.FlashbotsCallBundleResponse, error) {
+	err := r.limiter.Wait(context.Background())
+	if err != nil {
+		return flashbotsrpc.FlashbotsCallBundleResponse{}, err
+	}
+	return r.client.FlashbotsCallBundle(privKey, param)
+}
@@ -0,0 +1,49 @@
+package relay
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Policy is how requests failed with an error class are handled
+type Policy struct {
+	// MaxRetries is number of times request is resent, 0 gives up after the first failure
+	MaxRetries int
+	// backoff before retry n is random in [0, min(MaxBackoff, BaseBackoff * 2^n)]
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// TripsBreaker counts failure towards opening relay circuit breaker
+	TripsBreaker bool
+	// Fatal means further requests will fail as well, agent stops
+	Fatal bool
+}
+
+// DefaultPolicies retries transient failures, stops agents on rejected signatures
+// and keeps failures of overloaded or unreachable relay from reaching it through circuit breaker
+func DefaultPolicies() map[ErrorClass]Policy {
+	return map[ErrorClass]Policy{
+		ClassTransport:     {MaxRetries: 2, BaseBackoff: 50 * time.Millisecond, MaxBackoff: time.Second, TripsBreaker: true},
+		ClassRateLimited:   {TripsBreaker: true},
+		ClassServer:        {MaxRetries: 1, BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, TripsBreaker: true},
+		ClassRPC:           {},
+		ClassSignature:     {Fatal: true},
+		ClassBundleInvalid: {},
+		ClassCircuitOpen:   {},
+		ClassUnknown:       {},
+	}
+}
+
+// Backoff returns delay before retry attempt (starting at 0) with full jitter
+func (p Policy) Backoff(attempt int) time.Duration {
+	backoff := p.BaseBackoff
+	for i := 0; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
@@ -0,0 +1,43 @@
+package relay
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/metachris/flashbotsrpc"
+)
+
+// Client accepts bundles from agents, implemented by *HTTPClient, *Resilient, *RateLimited, *DryRun and *flashbotsrpc.FlashbotsRPC
+type Client interface {
+	FlashbotsSendBundle(privKey *ecdsa.PrivateKey, param flashbotsrpc.FlashbotsSendBundleRequest) (flashbotsrpc.FlashbotsSendBundleResponse, error)
+	FlashbotsCallBundle(privKey *ecdsa.PrivateKey, param flashbotsrpc.FlashbotsCallBundleParam) (flashbotsrpc.FlashbotsCallBundleResponse, error)
+}
+
+// New returns client of flashbots compatible relay at url
+func New(url string) *HTTPClient {
+	return NewHTTPClient(url, DefaultTimeout)
+}
+
+// CallBundleGasUsed simulates signed tx as a single tx bundle for targetBlock and returns gas it used
+func CallBundleGasUsed(relay Client, relayKey *ecdsa.PrivateKey, tx *types.Transaction, targetBlock uint64) (uint64, error) {
+	txBytes, err := tx.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	res, err := relay.FlashbotsCallBundle(relayKey, flashbotsrpc.FlashbotsCallBundleParam{
+		Txs:              []string{hexutil.Encode(txBytes)},
+		BlockNumber:      fmt.Sprintf("0x%x", targetBlock),
+		StateBlockNumber: "latest",
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(res.Results) != 1 {
+		return 0, fmt.Errorf("expected 1 tx result, got %d", len(res.Results))
+	}
+	if res.Results[0].Error != "" {
+		return 0, fmt.Errorf("bundle simulation failed: %s %s", res.Results[0].Error, res.Results[0].Revert)
+	}
+	return uint64(res.Results[0].GasUsed), nil
+}
@@ -0,0 +1,87 @@
+package relay
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"github.com/metachris/flashbotsrpc"
+	"time"
+)
+
+// Resilient wraps relay client shared by all agents sending to the relay.
+// Failed requests are retried according to policy of their error class, counted in Stats
+// and relay is not contacted while its circuit breaker is open.
+type Resilient struct {
+	client   Client
+	policies map[ErrorClass]Policy
+	breaker  *CircuitBreaker
+	Stats    *Stats
+}
+
+func NewResilient(client Client, policies map[ErrorClass]Policy, breaker *CircuitBreaker) *Resilient {
+	return &Resilient{
+		client:   client,
+		policies: policies,
+		breaker:  breaker,
+		Stats:    NewStats(),
+	}
+}
+
+// Policy returns policy of error class
+func (r *Resilient) Policy(class ErrorClass) Policy {
+	return r.policies[class]
+}
+
+// BreakerOpen reports if requests to relay are currently stopped by circuit breaker
+func (r *Resilient) BreakerOpen() bool {
+	return r.breaker.Open()
+}
+
+func (r *Resilient) FlashbotsSendBundle(privKey *ecdsa.PrivateKey, param flashbotsrpc.FlashbotsSendBundleRequest) (res flashbotsrpc.FlashbotsSendBundleResponse, err error) {
+	err = r.do(func() error {
+		res, err = r.client.FlashbotsSendBundle(privKey, param)
+		return err
+	})
+	return res, err
+}
+
+func (r *Resilient) FlashbotsCallBundle(privKey *ecdsa.PrivateKey, param flashbotsrpc.FlashbotsCallBundleParam) (res flashbotsrpc.FlashbotsCallBundleResponse, err error) {
+	err = r.do(func() error {
+		res, err = r.client.FlashbotsCallBundle(privKey, param)
+		return err
+	})
+	return res, err
+}
+
+func (r *Resilient) do(request func() error) error {
+	for attempt := 0; ; attempt++ {
+		if !r.breaker.Allow() {
+			err := &Error{Class: ClassCircuitOpen, Message: "relay circuit breaker is open"}
+			r.Stats.Record(err)
+			return err
+		}
+		err := request()
+		r.Stats.Record(err)
+		if err == nil {
+			r.breaker.Success()
+			return nil
+		}
+
+		class := Classify(err)
+		policy := r.policies[class]
+		if policy.TripsBreaker {
+			if r.breaker.Failure() {
+				fmt.Println("relay circuit breaker opened", "class", class, "error", err)
+			}
+		} else {
+			// relay answered, it's reachable
+			r.breaker.Success()
+		}
+		if relayErr, ok := err.(*Error); ok && relayErr.RetryAfter > 0 {
+			r.breaker.OpenFor(relayErr.RetryAfter)
+		}
+		if attempt >= policy.MaxRetries {
+			return err
+		}
+		time.Sleep(policy.Backoff(attempt))
+	}
+}
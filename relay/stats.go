@@ -0,0 +1,55 @@
+package relay
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Stats counts request outcomes by error class
+type Stats struct {
+	ok     atomic.Uint64
+	counts map[ErrorClass]*atomic.Uint64
+}
+
+func NewStats() *Stats {
+	counts := make(map[ErrorClass]*atomic.Uint64)
+	for _, class := range Classes {
+		counts[class] = new(atomic.Uint64)
+	}
+	return &Stats{counts: counts}
+}
+
+func (s *Stats) Record(err error) {
+	if s == nil {
+		return
+	}
+	if err == nil {
+		s.ok.Add(1)
+		return
+	}
+	s.counts[Classify(err)].Add(1)
+}
+
+func (s *Stats) Ok() uint64 {
+	return s.ok.Load()
+}
+
+func (s *Stats) Count(class ErrorClass) uint64 {
+	counter, ok := s.counts[class]
+	if !ok {
+		return 0
+	}
+	return counter.Load()
+}
+
+// String returns counts as key=value pairs, classes without errors are skipped
+func (s *Stats) String() string {
+	parts := []string{fmt.Sprintf("ok=%d", s.Ok())}
+	for _, class := range Classes {
+		if count := s.Count(class); count > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", class, count))
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,119 @@
+package transport
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Options tunes transport shared by http clients of all agents
+type Options struct {
+	// idle keep-alive connections kept per host, should be at least the number of requests in flight
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	// HTTP2 negotiates http/2 with TLS endpoints
+	HTTP2 bool
+}
+
+// NewTransport creates transport with connection pool sized for high request rates.
+// Default transport keeps only 2 idle connections per host, so concurrent requests keep opening new connections.
+func NewTransport(options Options) *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     options.HTTP2,
+		MaxIdleConnsPerHost:   options.MaxIdleConnsPerHost,
+		IdleConnTimeout:       options.IdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// Endpoint is per endpoint request options
+type Endpoint struct {
+	// headers added to every request
+	Headers map[string]string
+	// static bearer token, ignored if JWTSecret is set
+	BearerToken string
+	// HS256 secret of bearer JWT issued for every request, like engine api authentication
+	JWTSecret []byte
+	// Gzip compresses request bodies, endpoint has to accept Content-Encoding: gzip
+	Gzip bool
+}
+
+// NewClient creates http client of endpoint using shared transport, timeout 0 means no timeout
+func NewClient(transport http.RoundTripper, timeout time.Duration, endpoint Endpoint) *http.Client {
+	return &http.Client{
+		Transport: &endpointTransport{next: transport, endpoint: endpoint},
+		Timeout:   timeout,
+	}
+}
+
+type endpointTransport struct {
+	next     http.RoundTripper
+	endpoint Endpoint
+}
+
+func (t *endpointTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// round tripper must not modify the original request
+	req = req.Clone(req.Context())
+	for name, value := range t.endpoint.Headers {
+		req.Header.Set(name, value)
+	}
+	if len(t.endpoint.JWTSecret) > 0 {
+		token, err := NewJWT(t.endpoint.JWTSecret, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if t.endpoint.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+t.endpoint.BearerToken)
+	}
+	if t.endpoint.Gzip && req.Body != nil && req.Header.Get("Content-Encoding") == "" {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		var compressed bytes.Buffer
+		zw := gzip.NewWriter(&compressed)
+		_, err = zw.Write(body)
+		if closeErr := zw.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		data := compressed.Bytes()
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+		req.ContentLength = int64(len(data))
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	return t.next.RoundTrip(req)
+}
+
+// ParseHeaders parses comma separated list of name:value headers
+func ParseHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	if s == "" {
+		return headers, nil
+	}
+	for _, header := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q, expected name:value", header)
+		}
+		headers[name] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
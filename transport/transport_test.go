@@ -0,0 +1,143 @@
+package transport
+
+import (
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientAddsHeadersAndAuth(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	type received struct {
+		header http.Header
+		body   string
+	}
+	var last received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reader io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			reader = zr
+		}
+		body, _ := io.ReadAll(reader)
+		last = received{header: r.Header.Clone(), body: string(body)}
+	}))
+	defer server.Close()
+
+	tr := NewTransport(Options{MaxIdleConnsPerHost: 4, IdleConnTimeout: time.Minute})
+	headers, err := ParseHeaders("X-Api-Key: abc, X-Builder:v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := NewClient(tr, time.Second, Endpoint{Headers: headers, BearerToken: "token", Gzip: true}).Post(server.URL, "application/json", strings.NewReader(`{"id":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if last.header.Get("X-Api-Key") != "abc" || last.header.Get("X-Builder") != "v2" {
+		t.Errorf("headers %v, expected X-Api-Key and X-Builder", last.header)
+	}
+	if auth := last.header.Get("Authorization"); auth != "Bearer token" {
+		t.Errorf("authorization %q, expected bearer token", auth)
+	}
+	if last.header.Get("Content-Encoding") != "gzip" || last.body != `{"id":1}` {
+		t.Errorf("body %q with encoding %q, expected gzipped request", last.body, last.header.Get("Content-Encoding"))
+	}
+
+	before := time.Now().Unix()
+	resp, err = NewClient(tr, time.Second, Endpoint{BearerToken: "ignored", JWTSecret: secret}).Post(server.URL, "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	token := strings.TrimPrefix(last.header.Get("Authorization"), "Bearer ")
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("authorization %q is not bearer jwt", last.header.Get("Authorization"))
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if base64.RawURLEncoding.EncodeToString(mac.Sum(nil)) != parts[2] {
+		t.Error("jwt signature doesn't match secret")
+	}
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims struct {
+		IssuedAt int64 `json:"iat"`
+	}
+	err = json.Unmarshal(claimsJSON, &claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.IssuedAt < before || claims.IssuedAt > time.Now().Unix() {
+		t.Errorf("jwt issued at %d, expected now", claims.IssuedAt)
+	}
+	if last.header.Get("Content-Encoding") != "" {
+		t.Error("body compressed without gzip option")
+	}
+}
+
+func TestParseHeadersRejectsInvalid(t *testing.T) {
+	for _, s := range []string{"novalue", ":value", "a:b,,c:d"} {
+		if _, err := ParseHeaders(s); err == nil {
+			t.Errorf("%q parsed", s)
+		}
+	}
+}
+
+func TestSharedTransportReusesConnections(t *testing.T) {
+	var connections atomic.Int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond)
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	// clients of 8 agents, each sending 50 requests one at a time
+	const agents = 8
+	tr := NewTransport(Options{MaxIdleConnsPerHost: agents, IdleConnTimeout: time.Minute})
+	var wg sync.WaitGroup
+	for i := 0; i < agents; i++ {
+		client := NewClient(tr, time.Second, Endpoint{})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{}`))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				_, _ = io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	if count := connections.Load(); count > agents {
+		t.Errorf("%d connections opened for %d agents", count, agents)
+	}
+}
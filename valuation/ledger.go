@@ -0,0 +1,70 @@
+package valuation
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"sync"
+)
+
+type auction struct {
+	block uint64
+	slot  string
+}
+
+// Ledger keeps valuations agents drew for auctions of recent blocks, shared by agents and inclusion tracker
+type Ledger struct {
+	mu     sync.Mutex
+	values map[auction]map[common.Address]*big.Int
+}
+
+func NewLedger() *Ledger {
+	return &Ledger{values: make(map[auction]map[common.Address]*big.Int)}
+}
+
+// Record stores valuation of bidder for slot in block, nil ledger ignores it
+func (l *Ledger) Record(block uint64, slot *big.Int, bidder common.Address, value *big.Int) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := auction{block: block, slot: slot.String()}
+	if l.values[key] == nil {
+		l.values[key] = make(map[common.Address]*big.Int)
+	}
+	l.values[key][bidder] = value
+}
+
+// Value returns valuation of bidder for slot in block or nil if it's unknown
+func (l *Ledger) Value(block uint64, slot *big.Int, bidder common.Address) *big.Int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.values[auction{block: block, slot: slot.String()}][bidder]
+}
+
+// Max returns the highest valuation of slot in block and its bidder, nil if there are none
+func (l *Ledger) Max(block uint64, slot *big.Int) (*big.Int, common.Address) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var (
+		max    *big.Int
+		bidder common.Address
+	)
+	for addr, value := range l.values[auction{block: block, slot: slot.String()}] {
+		if max == nil || value.Cmp(max) > 0 {
+			max, bidder = value, addr
+		}
+	}
+	return max, bidder
+}
+
+// Prune drops valuations of blocks before block
+func (l *Ledger) Prune(block uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key := range l.values {
+		if key.block < block {
+			delete(l.values, key)
+		}
+	}
+}
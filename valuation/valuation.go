@@ -0,0 +1,114 @@
+package valuation
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+)
+
+const (
+	ModelNone      = "none"      // no valuation, bids are not capped
+	ModelFixed     = "fixed"     // always mean
+	ModelUniform   = "uniform"   // uniform in [mean-spread, mean+spread]
+	ModelNormal    = "normal"    // normal with standard deviation spread, truncated at 0
+	ModelLogNormal = "lognormal" // lognormal with given mean and standard deviation spread
+)
+
+func ParseModel(s string) (string, error) {
+	switch s {
+	case ModelNone, ModelFixed, ModelUniform, ModelNormal, ModelLogNormal:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown valuation model %s, expected none, fixed, uniform, normal or lognormal", s)
+	}
+}
+
+// Distribution draws private valuations of winning a slot in wei
+type Distribution interface {
+	Draw() *big.Int
+}
+
+// New returns distribution of model with mean and spread in wei, nil for ModelNone
+func New(model string, mean, spread float64, rng *rand.Rand) (Distribution, error) {
+	if model == ModelNone {
+		return nil, nil
+	}
+	if mean <= 0 || spread < 0 {
+		return nil, fmt.Errorf("valuation mean must be > 0 and spread >= 0")
+	}
+	switch model {
+	case ModelFixed:
+		return fixed(mean), nil
+	case ModelUniform:
+		if spread > mean {
+			return nil, fmt.Errorf("uniform valuation spread can't exceed mean")
+		}
+		return &uniform{min: mean - spread, width: 2 * spread, rng: rng}, nil
+	case ModelNormal:
+		return &normal{mean: mean, std: spread, rng: rng}, nil
+	case ModelLogNormal:
+		// parameters of the underlying normal distribution that give mean and spread
+		sigma2 := math.Log1p(spread * spread / (mean * mean))
+		return &logNormal{mu: math.Log(mean) - sigma2/2, sigma: math.Sqrt(sigma2), rng: rng}, nil
+	default:
+		return nil, fmt.Errorf("unknown valuation model %s", model)
+	}
+}
+
+func toWei(v float64) *big.Int {
+	if v <= 0 {
+		return new(big.Int)
+	}
+	wei, _ := big.NewFloat(v).Int(nil)
+	return wei
+}
+
+// Bounded is implemented by distributions with the highest value
+type Bounded interface {
+	Max() *big.Int
+}
+
+type fixed float64
+
+func (f fixed) Draw() *big.Int {
+	return toWei(float64(f))
+}
+
+func (f fixed) Max() *big.Int {
+	return toWei(float64(f))
+}
+
+type uniform struct {
+	min   float64
+	width float64
+	rng   *rand.Rand
+}
+
+func (u *uniform) Draw() *big.Int {
+	return toWei(u.min + u.rng.Float64()*u.width)
+}
+
+func (u *uniform) Max() *big.Int {
+	return toWei(u.min + u.width)
+}
+
+type normal struct {
+	mean float64
+	std  float64
+	rng  *rand.Rand
+}
+
+func (n *normal) Draw() *big.Int {
+	return toWei(n.mean + n.rng.NormFloat64()*n.std)
+}
+
+type logNormal struct {
+	mu    float64
+	sigma float64
+	rng   *rand.Rand
+}
+
+func (l *logNormal) Draw() *big.Int {
+	return toWei(math.Exp(l.mu + l.rng.NormFloat64()*l.sigma))
+}
@@ -0,0 +1,44 @@
+package valuation
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestDistributionsKeepMean(t *testing.T) {
+	const mean, spread = 1e16, 4e15
+	for _, model := range []string{ModelFixed, ModelUniform, ModelNormal, ModelLogNormal} {
+		t.Run(model, func(t *testing.T) {
+			distribution, err := New(model, mean, spread, rand.New(rand.NewSource(1)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			const n = 100000
+			var sum, sumSq float64
+			for i := 0; i < n; i++ {
+				value, _ := distribution.Draw().Float64()
+				if value < 0 {
+					t.Fatalf("negative valuation %f", value)
+				}
+				sum += value
+				sumSq += value * value
+			}
+			sampleMean := sum / n
+			std := math.Sqrt(sumSq/n - sampleMean*sampleMean)
+			if math.Abs(sampleMean-mean) > 0.02*mean {
+				t.Errorf("mean %e, expected %e", sampleMean, float64(mean))
+			}
+			expectedStd := spread
+			switch model {
+			case ModelFixed:
+				expectedStd = 0
+			case ModelUniform:
+				expectedStd = spread / math.Sqrt(3)
+			}
+			if math.Abs(std-expectedStd) > 0.05*spread {
+				t.Errorf("standard deviation %e, expected %e", std, expectedStd)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package wallet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// MockClef implements the part of clef external API used by ClefSigner (account_list, account_signTransaction)
+// with local keys and no confirmation prompts.
+// Use rpc.DialInProc(mock.Server()) in tests or serve it over http to try out -clef setup.
+type MockClef struct {
+	addresses []common.Address
+	keys      map[common.Address]*ecdsa.PrivateKey
+}
+
+func NewMockClef(keys []*ecdsa.PrivateKey) *MockClef {
+	mock := &MockClef{keys: make(map[common.Address]*ecdsa.PrivateKey)}
+	for _, key := range keys {
+		address := crypto.PubkeyToAddress(key.PublicKey)
+		mock.addresses = append(mock.addresses, address)
+		mock.keys[address] = key
+	}
+	return mock
+}
+
+func (m *MockClef) Server() *gethrpc.Server {
+	server := gethrpc.NewServer()
+	// registration only fails for invalid receivers
+	if err := server.RegisterName("account", &mockClefAPI{m}); err != nil {
+		panic(err)
+	}
+	return server
+}
+
+type mockClefAPI struct {
+	mock *MockClef
+}
+
+func (api *mockClefAPI) Version(ctx context.Context) (string, error) {
+	return "mock", nil
+}
+
+func (api *mockClefAPI) List(ctx context.Context) ([]common.Address, error) {
+	return api.mock.addresses, nil
+}
+
+func (api *mockClefAPI) SignTransaction(ctx context.Context, args apitypes.SendTxArgs, methodSelector *string) (*clefSignTransactionResult, error) {
+	key, ok := api.mock.keys[args.From.Address()]
+	if !ok {
+		return nil, fmt.Errorf("unknown account %s", args.From.Address().Hex())
+	}
+	if args.ChainID == nil {
+		return nil, fmt.Errorf("chain id is required")
+	}
+	signedTx, err := types.SignTx(args.ToTransaction(), types.LatestSignerForChainID(args.ChainID.ToInt()), key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signedTx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &clefSignTransactionResult{Raw: raw, Tx: signedTx}, nil
+}
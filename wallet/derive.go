@@ -0,0 +1,46 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	hdwallet "github.com/ethereum-optimism/go-ethereum-hdwallet"
+	"strings"
+)
+
+// DefaultPath is base derivation path of mnemonic wallets
+const DefaultPath = "m/44'/60'/0'/0"
+
+// Derive derives master wallet at basePath/masterIndex and searcher wallets at basePath/i for i in indices
+func Derive(mnemonic string, basePath string, masterIndex int, indices []int) (*ecdsa.PrivateKey, []*ecdsa.PrivateKey, error) {
+	wallet, err := hdwallet.NewFromMnemonic(mnemonic)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, i := range indices {
+		if i == masterIndex {
+			return nil, nil, fmt.Errorf("searcher wallet index %d is used by master wallet", i)
+		}
+	}
+
+	var accounts []*ecdsa.PrivateKey
+	for _, i := range append([]int{masterIndex}, indices...) {
+		if i < 0 {
+			return nil, nil, fmt.Errorf("account index must be >= 0, got %d", i)
+		}
+		path, err := hdwallet.ParseDerivationPath(fmt.Sprintf("%s/%d", strings.TrimSuffix(basePath, "/"), i))
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid derivation path: %w", err)
+		}
+		account, err := wallet.Derive(path, false)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to derive account %d: %w", i, err)
+		}
+		privKey, err := wallet.PrivateKey(account)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get private key for account %d: %w", i, err)
+		}
+		accounts = append(accounts, privKey)
+	}
+	return accounts[0], accounts[1:], nil
+}
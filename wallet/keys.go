@@ -0,0 +1,88 @@
+package wallet
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LoadKeystoreFile decrypts geth keystore file
+func LoadKeystoreFile(path string, password string) (*ecdsa.PrivateKey, error) {
+	keyJson, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := keystore.DecryptKey(keyJson, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore %s: %w", path, err)
+	}
+	return key.PrivateKey, nil
+}
+
+// LoadKeystoreDir decrypts all keystore files in dir, files are ordered by name
+func LoadKeystoreDir(dir string, password string) ([]*ecdsa.PrivateKey, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	var keys []*ecdsa.PrivateKey
+	for _, name := range names {
+		key, err := LoadKeystoreFile(filepath.Join(dir, name), password)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// LoadKeyFile reads hex encoded private keys, one per line. Empty lines and lines starting with # are skipped.
+func LoadKeyFile(path string) ([]*ecdsa.PrivateKey, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var keys []*ecdsa.PrivateKey
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, err := crypto.HexToECDSA(strings.TrimPrefix(text, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid key on line %d of %s: %w", line, path, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, scanner.Err()
+}
+
+// ReadPassword reads keystore password from file, empty path means empty password
+func ReadPassword(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	password, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(password), "\r\n"), nil
+}
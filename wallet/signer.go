@@ -0,0 +1,143 @@
+package wallet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"math/big"
+)
+
+// TxSigner signs transactions of a single account.
+// Key can be held locally or by an external signer such as clef.
+type TxSigner interface {
+	Address() common.Address
+	SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// KeySigner signs with a local private key
+type KeySigner struct {
+	pk *ecdsa.PrivateKey
+}
+
+func NewKeySigner(pk *ecdsa.PrivateKey) *KeySigner {
+	return &KeySigner{pk: pk}
+}
+
+func NewKeySigners(pks []*ecdsa.PrivateKey) []TxSigner {
+	signers := make([]TxSigner, len(pks))
+	for i, pk := range pks {
+		signers[i] = NewKeySigner(pk)
+	}
+	return signers
+}
+
+func (s *KeySigner) Address() common.Address {
+	return crypto.PubkeyToAddress(s.pk.PublicKey)
+}
+
+func (s *KeySigner) PrivateKey() *ecdsa.PrivateKey {
+	return s.pk
+}
+
+func (s *KeySigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.pk)
+}
+
+// ClefSigner signs using clef external API (account_signTransaction)
+type ClefSigner struct {
+	client  *gethrpc.Client
+	address common.Address
+}
+
+func NewClefSigner(client *gethrpc.Client, address common.Address) *ClefSigner {
+	return &ClefSigner{client: client, address: address}
+}
+
+// ClefSigners returns signers for all accounts clef is managing in the order of account_list
+func ClefSigners(client *gethrpc.Client) ([]TxSigner, error) {
+	var addresses []common.Address
+	err := client.CallContext(context.Background(), &addresses, "account_list")
+	if err != nil {
+		return nil, err
+	}
+	signers := make([]TxSigner, len(addresses))
+	for i, address := range addresses {
+		signers[i] = NewClefSigner(client, address)
+	}
+	return signers, nil
+}
+
+func (s *ClefSigner) Address() common.Address {
+	return s.address
+}
+
+type clefSignTransactionResult struct {
+	Raw hexutil.Bytes      `json:"raw"`
+	Tx  *types.Transaction `json:"tx"`
+}
+
+func (s *ClefSigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	var result clefSignTransactionResult
+	err := s.client.CallContext(context.Background(), &result, "account_signTransaction", toSendTxArgs(s.address, tx, chainID))
+	if err != nil {
+		return nil, err
+	}
+	signedTx := new(types.Transaction)
+	err = signedTx.UnmarshalBinary(result.Raw)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), signedTx)
+	if err != nil {
+		return nil, err
+	}
+	if sender != s.address || signedTx.Nonce() != tx.Nonce() {
+		return nil, fmt.Errorf("clef returned different transaction")
+	}
+	return signedTx, nil
+}
+
+func toSendTxArgs(from common.Address, tx *types.Transaction, chainID *big.Int) *apitypes.SendTxArgs {
+	data := hexutil.Bytes(tx.Data())
+	args := &apitypes.SendTxArgs{
+		From:    common.NewMixedcaseAddress(from),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   hexutil.Big(*tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Data:    &data,
+		ChainID: (*hexutil.Big)(chainID),
+	}
+	if tx.To() != nil {
+		to := common.NewMixedcaseAddress(*tx.To())
+		args.To = &to
+	}
+	switch tx.Type() {
+	case types.LegacyTxType:
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	case types.AccessListTxType:
+		accessList := tx.AccessList()
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+		args.AccessList = &accessList
+	default:
+		accessList := tx.AccessList()
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+		args.AccessList = &accessList
+	}
+	return args
+}
+
+// RelayKey returns key used to sign relay requests (X-Flashbots-Signature).
+// Local keys sign their own requests, external signers get a random identity.
+func RelayKey(signer TxSigner) (*ecdsa.PrivateKey, error) {
+	if keySigner, ok := signer.(*KeySigner); ok {
+		return keySigner.PrivateKey(), nil
+	}
+	return crypto.GenerateKey()
+}
@@ -0,0 +1,184 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// well known development mnemonic and its first accounts
+const testMnemonic = "test test test test test test test test test test test junk"
+
+var testAddresses = []common.Address{
+	common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"),
+	common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"),
+	common.HexToAddress("0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC"),
+}
+
+func TestDeriveKnownAddresses(t *testing.T) {
+	master, searchers, err := Derive(testMnemonic, DefaultPath, 0, []int{2, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address := crypto.PubkeyToAddress(master.PublicKey); address != testAddresses[0] {
+		t.Errorf("master %s, expected %s", address.Hex(), testAddresses[0].Hex())
+	}
+	if len(searchers) != 2 {
+		t.Fatalf("%d searchers, expected 2", len(searchers))
+	}
+	for i, index := range []int{2, 1} {
+		if address := crypto.PubkeyToAddress(searchers[i].PublicKey); address != testAddresses[index] {
+			t.Errorf("searcher %d %s, expected %s", index, address.Hex(), testAddresses[index].Hex())
+		}
+	}
+
+	// trailing slash of base path is ignored, other paths derive other accounts
+	_, searchers, err = Derive(testMnemonic, DefaultPath+"/", 0, []int{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address := crypto.PubkeyToAddress(searchers[0].PublicKey); address != testAddresses[1] {
+		t.Errorf("searcher with trailing slash path %s, expected %s", address.Hex(), testAddresses[1].Hex())
+	}
+	_, searchers, err = Derive(testMnemonic, "m/44'/60'/1'/0", 0, []int{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address := crypto.PubkeyToAddress(searchers[0].PublicKey); address == testAddresses[1] {
+		t.Error("account of another path derived the same address")
+	}
+
+	if _, _, err := Derive(testMnemonic, DefaultPath, 1, []int{1}); err == nil {
+		t.Error("searcher derived at master index")
+	}
+	if _, _, err := Derive(testMnemonic, DefaultPath, 0, []int{-1}); err == nil {
+		t.Error("searcher derived at negative index")
+	}
+	if _, _, err := Derive("not a mnemonic", DefaultPath, 0, nil); err == nil {
+		t.Error("invalid mnemonic accepted")
+	}
+}
+
+func TestLoadKeystoreAndKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	var keys []*ecdsa.PrivateKey
+	for i := 0; i < 2; i++ {
+		key, _ := crypto.GenerateKey()
+		keys = append(keys, key)
+	}
+
+	keystoreDir := filepath.Join(dir, "keystore")
+	ks := keystore.NewKeyStore(keystoreDir, keystore.LightScryptN, keystore.LightScryptP)
+	for _, key := range keys {
+		// file names start with creation time, so keys are loaded in the order of import
+		_, err := ks.ImportECDSA(key, "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	accounts := ks.Accounts()
+	err := os.WriteFile(filepath.Join(keystoreDir, ".hidden"), []byte("not a key"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	passwordFile := filepath.Join(dir, "password")
+	err = os.WriteFile(passwordFile, []byte("secret\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	password, err := ReadPassword(passwordFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadKeystoreDir(keystoreDir, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != len(keys) {
+		t.Fatalf("loaded %d keys from keystore, expected %d", len(loaded), len(keys))
+	}
+	for i := range keys {
+		if !loaded[i].Equal(keys[i]) {
+			t.Errorf("keystore key %d differs", i)
+		}
+	}
+	if _, err := LoadKeystoreFile(accounts[0].URL.Path, "wrong"); err == nil {
+		t.Error("keystore decrypted with wrong password")
+	}
+
+	keyFile := filepath.Join(dir, "keys")
+	content := "# searchers\n" + common.Bytes2Hex(crypto.FromECDSA(keys[0])) + "\n\n0x" + common.Bytes2Hex(crypto.FromECDSA(keys[1])) + "\n"
+	err = os.WriteFile(keyFile, []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err = LoadKeyFile(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != len(keys) || !loaded[0].Equal(keys[0]) || !loaded[1].Equal(keys[1]) {
+		t.Errorf("loaded %d keys from key file, expected the %d written", len(loaded), len(keys))
+	}
+	err = os.WriteFile(keyFile, []byte("0xzz\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadKeyFile(keyFile); err == nil {
+		t.Error("invalid key accepted")
+	}
+}
+
+func TestClefSignerRoundTrip(t *testing.T) {
+	_, keys, err := Derive(testMnemonic, DefaultPath, 0, []int{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := gethrpc.DialInProc(NewMockClef(keys).Server())
+	defer client.Close()
+	signers, err := ClefSigners(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signers) != 2 || signers[0].Address() != testAddresses[1] || signers[1].Address() != testAddresses[2] {
+		t.Fatalf("clef signers %v, expected accounts 1 and 2", signers)
+	}
+
+	chainID := big.NewInt(5)
+	to := common.Address{1}
+	txs := []*types.Transaction{
+		types.NewTx(&types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(1e9), Gas: 21000, To: &to, Value: big.NewInt(1)}),
+		types.NewTx(&types.AccessListTx{ChainID: chainID, Nonce: 2, GasPrice: big.NewInt(1e9), Gas: 30000, To: &to, Data: []byte{1},
+			AccessList: types.AccessList{{Address: to, StorageKeys: []common.Hash{{1}}}}}),
+		types.NewTx(&types.DynamicFeeTx{ChainID: chainID, Nonce: 3, GasTipCap: big.NewInt(1e9), GasFeeCap: big.NewInt(2e9), Gas: 30000, To: &to, Data: []byte{2}}),
+	}
+	for _, signer := range signers {
+		for _, tx := range txs {
+			signedTx, err := signer.SignTx(tx, chainID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sender, err := types.Sender(types.LatestSignerForChainID(chainID), signedTx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sender != signer.Address() {
+				t.Errorf("tx type %d signed by %s, expected %s", tx.Type(), sender.Hex(), signer.Address().Hex())
+			}
+			if signedTx.ChainId().Cmp(chainID) != 0 || signedTx.Type() != tx.Type() || signedTx.Nonce() != tx.Nonce() {
+				t.Errorf("signed tx type %d chain %s nonce %d, expected type %d chain %s nonce %d",
+					signedTx.Type(), signedTx.ChainId(), signedTx.Nonce(), tx.Type(), chainID, tx.Nonce())
+			}
+		}
+	}
+
+	if _, err := NewClefSigner(client, common.Address{2}).SignTx(txs[2], chainID); err == nil {
+		t.Error("clef signed tx of unknown account")
+	}
+}